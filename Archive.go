@@ -0,0 +1,230 @@
+package FlowG
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// archiveInstrument is the placeholder for the instrument of a file in the 'archiveLayout' config key.
+const archiveInstrument = "{instrument}"
+
+// unknownInstrument is used as the instrument directory for files without an instrument.
+const unknownInstrument = "unknown"
+
+// archivedName matches the name of a file to which FileMove added a timestamp prefix.
+var archivedName = regexp.MustCompile(`^(\d{17})_(.+)$`)
+
+// compressedSuffix is appended to the name of archived files that were compressed.
+const compressedSuffix = ".gz"
+
+// archiveMaintenanceInterval is how often a running watch compresses and removes old archived files.
+const archiveMaintenanceInterval = time.Hour
+
+// validateArchiveLayout checks that every element of layout stays within processedDir.
+func validateArchiveLayout(layout string) error {
+	if filepath.IsAbs(layout) {
+		return errors.New("archiveLayout must be a relative path")
+	}
+	for _, element := range strings.Split(filepath.ToSlash(layout), "/") {
+		if element == "" || element == "." || element == ".." {
+			return fmt.Errorf("archiveLayout contains an invalid path element '%s'", element)
+		}
+	}
+	return nil
+}
+
+// archivePartition returns the directory within processedDir for a file archived at archived, according to
+// config.archiveLayout. Every element of the layout is a Go time layout, such as '2006' or '01', or '{instrument}'
+// for the instrument of the file as returned by config.instrumentFunc.
+func (p *Pipeline) archivePartition(path string, archived time.Time) string {
+	if p.config.archiveLayout == "" {
+		return "."
+	}
+
+	elements := strings.Split(filepath.ToSlash(p.config.archiveLayout), "/")
+	for i, element := range elements {
+		if element != archiveInstrument {
+			elements[i] = archived.Format(element)
+			continue
+		}
+		instrument := ""
+		if p.config.instrumentFunc != nil {
+			instrument = p.config.instrumentFunc(path)
+		}
+		// The instrument is used as a directory name, so it may not escape processedDir
+		instrument = strings.NewReplacer("/", "_", "\\", "_").Replace(instrument)
+		if instrument == "" || instrument == "." || instrument == ".." {
+			instrument = unknownInstrument
+		}
+		elements[i] = instrument
+	}
+	return filepath.Join(elements...)
+}
+
+// parseArchivedName recovers the original name of an archived file and the time it was archived from the timestamp
+// prefix added by FileMove. Returns false if name has no such prefix.
+func parseArchivedName(name string) (string, time.Time, bool) {
+	match := archivedName.FindStringSubmatch(name)
+	if match == nil {
+		return name, time.Time{}, false
+	}
+	stamp := match[1][:14] + "." + match[1][14:]
+	archived, err := time.ParseInLocation(archiveTimestampLayout, stamp, time.Local)
+	if err != nil {
+		return name, time.Time{}, false
+	}
+	return match[2], archived, true
+}
+
+// CompactArchive compresses and removes old files in the processedDir of the default pipeline, see
+// Pipeline.CompactArchive.
+func CompactArchive() error {
+	return defaultPipeline().CompactArchive()
+}
+
+// CompactArchive gzips archived files in processedDir that are older than config.archiveCompressAfter and removes
+// archived files older than config.archiveRetention, together with the directories that become empty. The age of a
+// file is taken from the timestamp prefix added by FileMove. Every removed file is logged. A running watch does this
+// every hour when either key is set.
+func (p *Pipeline) CompactArchive() error {
+	compressAfter := p.config.archiveCompressAfter
+	retention := p.config.archiveRetention
+	if compressAfter == 0 && retention == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var dirs []string
+	compressed, removed := 0, 0
+	err := filepath.WalkDir(p.config.processedDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != p.config.processedDir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		// Skip temporary files of moves and compressions in progress
+		if strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		_, archived, ok := parseArchivedName(strings.TrimSuffix(d.Name(), compressedSuffix))
+		if !ok {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			archived = info.ModTime()
+		}
+		age := now.Sub(archived)
+
+		switch {
+		case retention > 0 && age > retention:
+			if err := os.Remove(path); err != nil {
+				p.Logging(fmt.Sprintf("Cannot remove expired archive '%s': %v", path, err), ERROR)
+				return nil
+			}
+			removed++
+			p.Logging(fmt.Sprintf("Removed expired archive '%s', archived %s", path, archived.Format(time.DateOnly)), INFO)
+		case compressAfter > 0 && age > compressAfter && !strings.HasSuffix(d.Name(), compressedSuffix):
+			if err := gzipFile(path); err != nil {
+				p.Logging(fmt.Sprintf("Cannot compress archive '%s': %v", path, err), ERROR)
+				return nil
+			}
+			compressed++
+			p.Logging(fmt.Sprintf("Compressed archive '%s'", path), DEBUG)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read processedDir: %w", err)
+	}
+
+	// Remove emptied partitions, deepest first so parents become empty as well
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if entries, err := os.ReadDir(dirs[i]); err == nil && len(entries) == 0 && retention > 0 {
+			if err = os.Remove(dirs[i]); err == nil {
+				p.Logging(fmt.Sprintf("Removed empty archive directory '%s'", dirs[i]), INFO)
+			}
+		}
+	}
+
+	if compressed > 0 || removed > 0 {
+		p.Logging(fmt.Sprintf("Archive maintenance compressed %d and removed %d files", compressed, removed), INFO)
+	}
+	return nil
+}
+
+// maintainArchive runs CompactArchive every archiveMaintenanceInterval until ctx is cancelled.
+func (p *Pipeline) maintainArchive(ctx context.Context) {
+	ticker := time.NewTicker(archiveMaintenanceInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.CompactArchive(); err != nil {
+			p.Logging(fmt.Sprintf("Archive maintenance failed: %v", err), ERROR)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// gzipFile replaces the file at path by a gzip compressed copy named path+'.gz', keeping its modification time.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst := path + compressedSuffix
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp")
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(path)
+	zw.ModTime = info.ModTime()
+	if _, err = io.Copy(zw, in); err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	_ = in.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,161 @@
+package FlowG
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchivePartition(t *testing.T) {
+	archived := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	cases := []struct {
+		name       string
+		layout     string
+		instrument string
+		expected   string
+	}{
+		{"Flat", "", "Analyser1", "."},
+		{"Per day", "2006/01/02", "Analyser1", filepath.Join("2026", "10", "17")},
+		{"Per instrument and month", "{instrument}/2006-01", "Analyser1", filepath.Join("Analyser1", "2026-10")},
+		{"Unknown instrument", "{instrument}", "", unknownInstrument},
+		{"Instrument with separator", "{instrument}", "lab/Analyser1", "lab_Analyser1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				archiveLayout: c.layout,
+				instrumentFunc: func(string) string {
+					return c.instrument
+				},
+			}
+			if actual := defaultPipeline().archivePartition("run.csv", archived); actual != c.expected {
+				t.Errorf("archivePartition: expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFileMoveArchiveLayout(t *testing.T) {
+	config = &configStruct{
+		glimsDir:      "./glims",
+		importDir:     "./import",
+		processedDir:  "./processed",
+		errorDir:      "./error",
+		logDir:        "./log",
+		logPrefix:     "Test",
+		logLvl:        WARNING,
+		archiveLayout: "2006/01/02",
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	for _, ok := range []bool{true, false} {
+		path := filepath.Join(config.importDir, "testFile.txt")
+		if err = os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Error creating test file: %v", err)
+		}
+		if err = FileMove(path, ok); err != nil {
+			t.Fatalf("FileMove returned error: %v", err)
+		}
+	}
+
+	processed, _ := filepath.Glob(filepath.Join(config.processedDir, time.Now().Format("2006/01/02"), "*_testFile.txt"))
+	if len(processed) != 1 {
+		t.Errorf("Expected 1 file in today's partition of processedDir, got %v", processed)
+	}
+	// The layout only applies to processedDir
+	failed, _ := filepath.Glob(filepath.Join(config.errorDir, "*_testFile.txt"))
+	if len(failed) != 1 {
+		t.Errorf("Expected 1 file directly in errorDir, got %v", failed)
+	}
+}
+
+func TestCompactArchive(t *testing.T) {
+	day := 24 * time.Hour
+	cases := []struct {
+		name           string
+		compressAfter  time.Duration
+		retention      time.Duration
+		wantPlain      int
+		wantCompressed int
+	}{
+		{"Disabled", 0, 0, 3, 0},
+		{"Compress only", 7 * day, 0, 1, 2},
+		{"Retention only", 0, 30 * day, 2, 0},
+		{"Compress and retention", 7 * day, 30 * day, 1, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				processedDir:         t.TempDir(),
+				logDir:               t.TempDir(),
+				logLvl:               CRITICAL,
+				archiveCompressAfter: c.compressAfter,
+				archiveRetention:     c.retention,
+			}
+
+			// Archived today, 10 days ago and 60 days ago
+			for _, age := range []time.Duration{0, 10 * day, 60 * day} {
+				archived := time.Now().Add(-age)
+				dir := filepath.Join(config.processedDir, archived.Format("2006/01/02"))
+				if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+					t.Fatalf("Error creating partition: %v", err)
+				}
+				name := strings.ReplaceAll(archived.Format(archiveTimestampLayout), ".", "") + "_run.csv"
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("Sample1;Compound1;1.5"), 0644); err != nil {
+					t.Fatalf("Error creating archived file: %v", err)
+				}
+			}
+
+			if err := CompactArchive(); err != nil {
+				t.Fatalf("CompactArchive returned error: %v", err)
+			}
+
+			var plain, compressed []string
+			_ = filepath.Walk(config.processedDir, func(path string, info os.FileInfo, err error) error {
+				switch {
+				case info.IsDir():
+				case strings.HasSuffix(path, compressedSuffix):
+					compressed = append(compressed, path)
+				default:
+					plain = append(plain, path)
+				}
+				return nil
+			})
+			if len(plain) != c.wantPlain || len(compressed) != c.wantCompressed {
+				t.Errorf("Expected %d plain and %d compressed files, got %v and %v", c.wantPlain, c.wantCompressed, plain, compressed)
+			}
+
+			for _, path := range compressed {
+				file, err := os.Open(path)
+				if err != nil {
+					t.Fatalf("Cannot open compressed file: %v", err)
+				}
+				zr, err := gzip.NewReader(file)
+				if err != nil {
+					t.Fatalf("Cannot read compressed file: %v", err)
+				}
+				content, err := io.ReadAll(zr)
+				_ = file.Close()
+				if err != nil || string(content) != "Sample1;Compound1;1.5" {
+					t.Errorf("Unexpected compressed content %q (%v)", content, err)
+				}
+			}
+		})
+	}
+}
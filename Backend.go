@@ -0,0 +1,237 @@
+package FlowG
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Available watcher backends for the 'watcherBackend' config key
+const (
+	BackendFsnotify = "fsnotify" // Event based watching using the notification API of the operating system (default)
+	BackendPoll     = "poll"     // Periodic directory listing, for network shares that do not deliver events
+)
+
+// Default interval of the polling backend when 'pollInterval' is not set
+const defaultPollInterval = 2 * time.Second
+
+// watchBackend reports files and directories that are created in the directories added to it.
+type watchBackend interface {
+	// Add starts watching the given directory, without reporting the entries it already contains.
+	Add(dir string) error
+	// Events returns the channel on which the paths of newly created entries are delivered.
+	Events() <-chan string
+	// Errors returns the channel on which non-fatal watch errors are delivered.
+	Errors() <-chan error
+	// Close stops watching and closes both channels.
+	Close() error
+}
+
+// newWatchBackend creates the watcher backend selected by config.watcherBackend.
+func (p *Pipeline) newWatchBackend() (watchBackend, error) {
+	switch p.config.watcherBackend {
+	case "", BackendFsnotify:
+		return newFsnotifyBackend()
+	case BackendPoll:
+		interval := p.config.pollInterval
+		if interval == 0 {
+			interval = defaultPollInterval
+		}
+		return newPollBackend(interval), nil
+	default:
+		return nil, fmt.Errorf("unknown watcher backend: %s", p.config.watcherBackend)
+	}
+}
+
+// fsnotifyBackend is the watchBackend based on fsnotify.
+type fsnotifyBackend struct {
+	watcher *fsnotify.Watcher
+	events  chan string
+}
+
+func newFsnotifyBackend() (*fsnotifyBackend, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &fsnotifyBackend{watcher: watcher, events: make(chan string)}
+	go func() {
+		defer close(b.events)
+		for event := range watcher.Events {
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				b.events <- event.Name
+			}
+		}
+	}()
+	return b, nil
+}
+
+func (b *fsnotifyBackend) Add(dir string) error {
+	return b.watcher.Add(dir)
+}
+
+func (b *fsnotifyBackend) Events() <-chan string {
+	return b.events
+}
+
+func (b *fsnotifyBackend) Errors() <-chan error {
+	return b.watcher.Errors
+}
+
+func (b *fsnotifyBackend) Close() error {
+	err := b.watcher.Close()
+	// Release the forwarding goroutine if it is blocked on an event nobody reads anymore
+	for range b.events {
+	}
+	return err
+}
+
+// pollBackend is the watchBackend that lists the watched directories every interval. Files are tracked by name, size
+// and modification time: a file is reported when it appears, or when it changed in between two polls. A file that is
+// still being processed when it is reported again is not queued twice, see watchRun.submit. Subdirectories are only
+// reported when they appear, changes to their contents are found by polling them.
+type pollBackend struct {
+	interval time.Duration
+	events   chan string
+	errors   chan error
+	done     chan struct{}
+	stopped  sync.WaitGroup
+
+	mu   sync.Mutex
+	dirs map[string]map[string]pollState
+}
+
+// pollState is the last seen state of a directory entry.
+type pollState struct {
+	size    int64
+	modTime time.Time
+}
+
+func newPollBackend(interval time.Duration) *pollBackend {
+	b := &pollBackend{
+		interval: interval,
+		events:   make(chan string),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]pollState),
+	}
+
+	b.stopped.Add(1)
+	go func() {
+		defer b.stopped.Done()
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-ticker.C:
+				b.poll()
+			}
+		}
+	}()
+	return b
+}
+
+func (b *pollBackend) Add(dir string) error {
+	dir = filepath.Clean(dir)
+	state, err := listDir(dir)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.dirs[dir]; !exists {
+		b.dirs[dir] = state
+	}
+	return nil
+}
+
+// poll lists all watched directories and reports new and changed entries.
+func (b *pollBackend) poll() {
+	b.mu.Lock()
+	dirs := make([]string, 0, len(b.dirs))
+	for dir := range b.dirs {
+		dirs = append(dirs, dir)
+	}
+	b.mu.Unlock()
+
+	for _, dir := range dirs {
+		current, err := listDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			// A watched subdirectory was removed, e.g. by a technician cleaning up an emptied run folder
+			b.mu.Lock()
+			delete(b.dirs, dir)
+			b.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			select {
+			case b.errors <- fmt.Errorf("cannot poll '%s': %w", dir, err):
+			case <-b.done:
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		previous := b.dirs[dir]
+		b.dirs[dir] = current
+		b.mu.Unlock()
+
+		for name, state := range current {
+			if seen, exists := previous[name]; exists && seen == state {
+				continue
+			}
+			select {
+			case b.events <- filepath.Join(dir, name):
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+func (b *pollBackend) Events() <-chan string {
+	return b.events
+}
+
+func (b *pollBackend) Errors() <-chan error {
+	return b.errors
+}
+
+func (b *pollBackend) Close() error {
+	close(b.done)
+	b.stopped.Wait()
+	close(b.events)
+	close(b.errors)
+	return nil
+}
+
+// listDir returns the current state of all entries in dir.
+func listDir(dir string) (map[string]pollState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]pollState, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			state[entry.Name()] = pollState{}
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+		state[entry.Name()] = pollState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return state, nil
+}
@@ -0,0 +1,300 @@
+package FlowG
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchBackend(t *testing.T) {
+	cases := []struct {
+		name    string
+		backend string
+	}{
+		{"fsnotify backend", BackendFsnotify},
+		{"Polling backend", BackendPoll},
+	}
+
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		pollInterval: 50 * time.Millisecond,
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.watcherBackend = c.backend
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			// Entries present before Add should not be reported
+			err = os.WriteFile(filepath.Join(config.importDir, "existing.txt"), []byte("data"), 0644)
+			if err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			backend, err := defaultPipeline().newWatchBackend()
+			if err != nil {
+				t.Fatalf("newWatchBackend() returned error %v", err)
+			}
+			err = backend.Add(config.importDir)
+			if err != nil {
+				t.Fatalf("Add() returned error %v", err)
+			}
+
+			newPath := filepath.Join(config.importDir, "new.txt")
+			err = os.WriteFile(newPath, []byte("data"), 0644)
+			if err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			select {
+			case path := <-backend.Events():
+				if path != filepath.Clean(newPath) {
+					t.Errorf("Expected event for '%s', got '%s'", filepath.Clean(newPath), path)
+				}
+			case err = <-backend.Errors():
+				t.Errorf("Unexpected watch error: %v", err)
+			case <-time.After(2 * time.Second):
+				t.Errorf("Timeout waiting for event")
+			}
+
+			err = backend.Close()
+			if err != nil {
+				t.Errorf("Close() returned error %v", err)
+			}
+			if _, ok := <-backend.Events(); ok {
+				t.Errorf("Expected the event channel to be closed")
+			}
+		})
+	}
+}
+
+func TestFileWatchContextPolling(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		stableQuietPeriod: 100 * time.Millisecond,
+		watcherBackend:    BackendPoll,
+		pollInterval:      50 * time.Millisecond,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	callbackInvoked := make(chan string, 2)
+	callback := func(path string) bool {
+		callbackInvoked <- filepath.Base(path)
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- FileWatchContext(ctx, callback)
+	}()
+
+	// Allow time for FileWatchContext to initialize
+	time.Sleep(100 * time.Millisecond)
+	err = os.WriteFile(filepath.Join(config.importDir, "testFile.txt"), []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+
+	select {
+	case name := <-callbackInvoked:
+		if name != "testFile.txt" {
+			t.Errorf("Callback invoked for '%s', expected 'testFile.txt'", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("FileWatchContext() got timeout, expected file detection")
+	}
+
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchContext() returned error %v", err)
+	}
+
+	errorFileList, _ := filepath.Glob(filepath.Join(config.errorDir, "*_testFile.txt"))
+	if len(errorFileList) != 1 {
+		t.Errorf("Expected 1 file in errorDir, got %d", len(errorFileList))
+	}
+	if len(callbackInvoked) != 0 {
+		t.Errorf("Callback was invoked more than once")
+	}
+}
+
+func TestPollBackendReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	subDir := filepath.Join(dir, "run_0412")
+	if err := os.Mkdir(subDir, os.ModePerm); err != nil {
+		t.Fatalf("Error creating subdirectory: %v", err)
+	}
+
+	backend := newPollBackend(time.Hour)
+	defer backend.Close()
+	if err := backend.Add(dir); err != nil {
+		t.Fatalf("Add() returned error %v", err)
+	}
+
+	// poll is called directly, so every step is exactly one poll
+	poll := func() []string {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			backend.poll()
+		}()
+		var paths []string
+		for {
+			select {
+			case path := <-backend.Events():
+				paths = append(paths, filepath.Base(path))
+			case <-done:
+				return paths
+			}
+		}
+	}
+
+	filePath := filepath.Join(dir, "plate1.csv")
+	if err := os.WriteFile(filePath, []byte("header\n"), 0644); err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	if paths := poll(); len(paths) != 1 || paths[0] != "plate1.csv" {
+		t.Fatalf("Expected an event for the new file, got %v", paths)
+	}
+	if paths := poll(); len(paths) != 0 {
+		t.Errorf("Expected no events for an unchanged file, got %v", paths)
+	}
+
+	// A file that changed is reported again, a subdirectory whose contents change is not
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(filePath, []byte("header\nrow\n"), 0644); err != nil {
+		t.Fatalf("Error writing test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, "sample.txt"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Error writing to subdirectory: %v", err)
+	}
+	_ = os.Chtimes(subDir, later, later)
+	if paths := poll(); len(paths) != 1 || paths[0] != "plate1.csv" {
+		t.Errorf("Expected an event for the changed file only, got %v", paths)
+	}
+
+	// A file replaced by another one with the same size is reported by its modification time
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("Error removing test file: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("header\nrow\n"), 0644); err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	_ = os.Chtimes(filePath, later, later)
+	if paths := poll(); len(paths) != 1 || paths[0] != "plate1.csv" {
+		t.Errorf("Expected an event for the replaced file, got %v", paths)
+	}
+}
+
+func TestFileWatchPollingGrowingFile(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		stableQuietPeriod: 300 * time.Millisecond,
+		watcherBackend:    BackendPoll,
+		pollInterval:      20 * time.Millisecond,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	callbackInvoked := make(chan int64, 5)
+	callback := func(path string) bool {
+		info, err := os.Stat(path)
+		if err == nil {
+			callbackInvoked <- info.Size()
+		}
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- FileWatchContext(ctx, callback)
+	}()
+
+	// Allow time for FileWatchContext to initialize
+	time.Sleep(100 * time.Millisecond)
+	filePath := filepath.Join(config.importDir, "testFile.txt")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	// The file changes at nearly every poll while it is written, but is handed to the callback only once
+	for i := 0; i < 10; i++ {
+		if _, err = file.WriteString("row\n"); err != nil {
+			t.Fatalf("Error writing test file: %v", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+	_ = file.Close()
+
+	select {
+	case size := <-callbackInvoked:
+		if size != 40 {
+			t.Errorf("Callback invoked with %d bytes, expected the complete file of 40 bytes", size)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("FileWatchContext() got timeout, expected file detection")
+	}
+
+	// Give a second callback the time to show up
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchContext() returned error %v", err)
+	}
+	if len(callbackInvoked) != 0 {
+		t.Errorf("Callback was invoked more than once")
+	}
+}
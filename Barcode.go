@@ -0,0 +1,225 @@
+package FlowG
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CheckDigit is the check digit algorithm of a BarcodeRule. The last character of the barcode is the check digit.
+type CheckDigit string
+
+// Available check digit algorithms
+const (
+	CheckDigitNone         CheckDigit = ""                // No check digit (default)
+	CheckDigitMod10        CheckDigit = "mod10"           // Luhn, digits only
+	CheckDigitMod11        CheckDigit = "mod11"           // Weights 2 to 7 from the right, 'X' for 10, digits only
+	CheckDigitISO7064Mod11 CheckDigit = "iso7064-mod11-2" // ISO 7064 MOD 11-2, 'X' for 10, digits only
+	CheckDigitISO7064Mod37 CheckDigit = "iso7064-mod37-2" // ISO 7064 MOD 37-2, '*' for 36, digits and letters A-Z
+)
+
+// BarcodeCase is the case folding of a BarcodeRule.
+type BarcodeCase uint8
+
+// Available case foldings
+const (
+	CaseKeep  BarcodeCase = iota // Leave the case of the barcode unchanged (default)
+	CaseUpper                    // Convert the barcode to upper case
+	CaseLower                    // Convert the barcode to lower case
+)
+
+// iso7064Alphabet holds the characters of ISO 7064 MOD 37-2 in the order of their value.
+const iso7064Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ*"
+
+// BarcodeRule describes how the barcodes of an instrument are normalised and validated. Leading and trailing
+// whitespace is always removed. Then the first matching StripPrefixes entry is removed, the case is folded, Normalize
+// is called, and the result is checked against Pattern and CheckDigit.
+type BarcodeRule struct {
+	Instrument    string                               // Matched against SampleStruct.InstrumentID, wildcards allowed, empty matches any
+	StripPrefixes []string                             // Scanner prefixes removed from the barcode, such as ']C1'
+	Case          BarcodeCase                          // Case folding of the barcode
+	Normalize     func(barcode string) (string, error) // Optional custom step, may change the barcode or reject it
+	Pattern       string                               // Regular expression the complete barcode must match, empty allows any
+	CheckDigit    CheckDigit                           // Check digit algorithm, including the check digit in the barcode
+
+	pattern *regexp.Regexp
+}
+
+// BarcodeValidator normalises and validates barcodes before GlimsOutput writes them, see the 'barcodeValidator'
+// config key. The first rule matching the instrument of a sample applies, barcodes of other instruments are left
+// unchanged.
+type BarcodeValidator struct {
+	rules []BarcodeRule
+}
+
+// NewBarcodeValidator creates a BarcodeValidator from a list of rules, validating each rule.
+func NewBarcodeValidator(rules []BarcodeRule) (*BarcodeValidator, error) {
+	v := &BarcodeValidator{rules: make([]BarcodeRule, len(rules))}
+	for i, rule := range rules {
+		if rule.Case > CaseLower {
+			return nil, fmt.Errorf("barcode rule %d has an unknown case folding %d", i+1, rule.Case)
+		}
+		switch rule.CheckDigit {
+		case CheckDigitNone, CheckDigitMod10, CheckDigitMod11, CheckDigitISO7064Mod11, CheckDigitISO7064Mod37:
+		default:
+			return nil, fmt.Errorf("barcode rule %d has an unknown check digit algorithm '%s'", i+1, rule.CheckDigit)
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("barcode rule %d has an invalid pattern: %w", i+1, err)
+			}
+			rule.pattern = pattern
+		}
+		v.rules[i] = rule
+	}
+	return v, nil
+}
+
+// Normalize returns the normalised barcode of a sample of instrument, or an error describing why it is invalid. An
+// empty barcode is returned unchanged.
+func (v *BarcodeValidator) Normalize(instrument, barcode string) (string, error) {
+	for _, rule := range v.rules {
+		if matchWildcard(rule.Instrument, instrument) {
+			return rule.normalize(barcode)
+		}
+	}
+	return barcode, nil
+}
+
+// normalize applies the rule to barcode.
+func (r BarcodeRule) normalize(barcode string) (string, error) {
+	barcode = strings.TrimSpace(barcode)
+	for _, prefix := range r.StripPrefixes {
+		if prefix != "" && strings.HasPrefix(barcode, prefix) {
+			barcode = strings.TrimSpace(strings.TrimPrefix(barcode, prefix))
+			break
+		}
+	}
+	if barcode == "" {
+		return barcode, nil
+	}
+
+	switch r.Case {
+	case CaseUpper:
+		barcode = strings.ToUpper(barcode)
+	case CaseLower:
+		barcode = strings.ToLower(barcode)
+	}
+	if r.Normalize != nil {
+		var err error
+		if barcode, err = r.Normalize(barcode); err != nil {
+			return "", err
+		}
+	}
+	if r.pattern != nil && !r.pattern.MatchString(barcode) {
+		return "", fmt.Errorf("barcode '%s' does not match pattern '%s'", barcode, r.Pattern)
+	}
+	if r.CheckDigit != CheckDigitNone && !validCheckDigit(r.CheckDigit, barcode) {
+		return "", fmt.Errorf("barcode '%s' has an invalid %s check digit", barcode, r.CheckDigit)
+	}
+	return barcode, nil
+}
+
+// validCheckDigit reports whether the last character of barcode is the correct check digit of the characters
+// before it.
+func validCheckDigit(algorithm CheckDigit, barcode string) bool {
+	if len(barcode) < 2 {
+		return false
+	}
+	data, check := barcode[:len(barcode)-1], barcode[len(barcode)-1]
+	var want byte
+	var err error
+	switch algorithm {
+	case CheckDigitMod10:
+		want, err = mod10CheckDigit(data)
+	case CheckDigitMod11:
+		want, err = mod11CheckDigit(data)
+	case CheckDigitISO7064Mod11:
+		want, err = iso7064CheckDigit(data, 11)
+	case CheckDigitISO7064Mod37:
+		want, err = iso7064CheckDigit(data, 37)
+	}
+	return err == nil && check == want
+}
+
+// digits returns the values of the digits of s, or an error if s contains anything else.
+func digits(s string) ([]int, error) {
+	values := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return nil, errors.New("not a digit")
+		}
+		values[i] = int(s[i] - '0')
+	}
+	return values, nil
+}
+
+// mod10CheckDigit returns the Luhn check digit of data.
+func mod10CheckDigit(data string) (byte, error) {
+	values, err := digits(data)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0
+	for i := range values {
+		value := values[len(values)-1-i]
+		if i%2 == 0 {
+			value *= 2
+			if value > 9 {
+				value -= 9
+			}
+		}
+		sum += value
+	}
+	return byte('0' + (10-sum%10)%10), nil
+}
+
+// mod11CheckDigit returns the modulus 11 check digit of data, weighting the digits 2 to 7 from the right.
+func mod11CheckDigit(data string) (byte, error) {
+	values, err := digits(data)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0
+	for i := range values {
+		sum += values[len(values)-1-i] * (2 + i%6)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X', nil
+	}
+	return byte('0' + check), nil
+}
+
+// iso7064CheckDigit returns the ISO 7064 pure system check character of data for MOD 11-2 or MOD 37-2.
+func iso7064CheckDigit(data string, modulus int) (byte, error) {
+	alphabet := iso7064Alphabet[:modulus-1]
+	p := 0
+	for i := 0; i < len(data); i++ {
+		value := strings.IndexByte(alphabet, data[i])
+		if value < 0 {
+			return 0, errors.New("invalid character")
+		}
+		p = (p + value) * 2 % modulus
+	}
+	check := (modulus + 1 - p) % modulus
+	if modulus == 11 && check == 10 {
+		return 'X', nil
+	}
+	return iso7064Alphabet[check], nil
+}
+
+// normalizeBarcode normalises the barcode of sample according to config.barcodeValidator.
+func (p *Pipeline) normalizeBarcode(sample SampleStruct) (string, error) {
+	validator := p.config.barcodeValidator
+	if validator == nil {
+		return sample.Barcode, nil
+	}
+	barcode, err := validator.Normalize(sample.InstrumentID, sample.Barcode)
+	if err == nil && barcode != sample.Barcode {
+		p.Logging(fmt.Sprintf("Normalised barcode '%s' to '%s'", sample.Barcode, barcode), DEBUG)
+	}
+	return barcode, err
+}
@@ -0,0 +1,172 @@
+package FlowG
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidCheckDigit(t *testing.T) {
+	cases := []struct {
+		name      string
+		algorithm CheckDigit
+		barcode   string
+		want      bool
+	}{
+		{"Mod10 valid", CheckDigitMod10, "79927398713", true},
+		{"Mod10 wrong digit", CheckDigitMod10, "79927398710", false},
+		{"Mod10 letters", CheckDigitMod10, "7992739871A", false},
+		{"Mod11 valid", CheckDigitMod11, "0365327", true},
+		{"Mod11 wrong digit", CheckDigitMod11, "0365328", false},
+		{"ISO 7064 MOD 11-2 valid", CheckDigitISO7064Mod11, "0000000218250097", true},
+		{"ISO 7064 MOD 11-2 with X", CheckDigitISO7064Mod11, "079X", true},
+		{"ISO 7064 MOD 11-2 wrong digit", CheckDigitISO7064Mod11, "0000000218250096", false},
+		{"ISO 7064 MOD 37-2 valid", CheckDigitISO7064Mod37, "G123498654321H", true},
+		{"ISO 7064 MOD 37-2 wrong character", CheckDigitISO7064Mod37, "G123498654321G", false},
+		{"ISO 7064 MOD 37-2 lower case", CheckDigitISO7064Mod37, "g123498654321H", false},
+		{"Too short", CheckDigitMod10, "7", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := validCheckDigit(c.algorithm, c.barcode); got != c.want {
+				t.Errorf("Expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestBarcodeValidatorNormalize(t *testing.T) {
+	validator, err := NewBarcodeValidator([]BarcodeRule{
+		{Instrument: "QS5-*", StripPrefixes: []string{"]C1", "]A0"}, Case: CaseUpper, Pattern: `[A-Z]\d{12}[0-9A-Z*]`, CheckDigit: CheckDigitISO7064Mod37},
+		{Instrument: "Cobas", CheckDigit: CheckDigitMod10, Normalize: func(barcode string) (string, error) {
+			if strings.HasPrefix(barcode, "TEST") {
+				return "", errors.New("test barcode")
+			}
+			return strings.TrimLeft(barcode, "0"), nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewBarcodeValidator returned error: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		instrument  string
+		barcode     string
+		wantBarcode string
+		wantErr     string
+	}{
+		{"Valid barcode", "QS5-01", "G123498654321H", "G123498654321H", ""},
+		{"Whitespace, prefix and lower case", "QS5-01", " ]C1g123498654321h\r\n", "G123498654321H", ""},
+		{"Pattern mismatch", "QS5-01", "G12349865432H", "", "does not match pattern"},
+		{"Invalid check digit", "QS5-02", "G123498654321G", "", "invalid iso7064-mod37-2 check digit"},
+		{"Only whitespace", "QS5-01", "  ", "", ""},
+		{"Custom normalisation", "Cobas", "0079927398713", "79927398713", ""},
+		{"Custom rejection", "Cobas", "TEST1", "", "test barcode"},
+		{"Instrument without rule", "Alinity", " abc ", " abc ", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			barcode, err := validator.Normalize(c.instrument, c.barcode)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil || barcode != c.wantBarcode {
+				t.Errorf("Expected %q, got %q (%v)", c.wantBarcode, barcode, err)
+			}
+		})
+	}
+}
+
+func TestNewBarcodeValidator(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    BarcodeRule
+		wantErr string
+	}{
+		{"Valid rule", BarcodeRule{Pattern: `\d{10}`, CheckDigit: CheckDigitMod11}, ""},
+		{"Invalid pattern", BarcodeRule{Pattern: `[0-9`}, "invalid pattern"},
+		{"Instrument with brackets", BarcodeRule{Instrument: "QS5-["}, ""},
+		{"Unknown check digit", BarcodeRule{CheckDigit: "mod97"}, "unknown check digit algorithm 'mod97'"},
+		{"Unknown case folding", BarcodeRule{Case: 3}, "unknown case folding 3"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewBarcodeValidator([]BarcodeRule{c.rule})
+			if c.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGlimsOutputBarcodeValidator(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logLvl:       CRITICAL,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	config.barcodeValidator, err = NewBarcodeValidator([]BarcodeRule{{Case: CaseUpper, CheckDigit: CheckDigitMod10}})
+	if err != nil {
+		t.Fatalf("NewBarcodeValidator returned error: %v", err)
+	}
+
+	samples := []SampleStruct{
+		{Barcode: " 79927398713 ", TestName: "Test1", ResultText: "POS", InstrumentID: "Instrument1"},
+		{Barcode: "79927398710", TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"},
+		{Barcode: " ", TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"},
+	}
+	report, err := GlimsOutputReport("output", samples)
+	if err != nil {
+		t.Fatalf("GlimsOutputReport returned error: %v", err)
+	}
+
+	want := []Rejection{
+		{Index: 1, Barcode: "79927398710", Reason: "barcode '79927398710' has an invalid mod10 check digit"},
+		{Index: 2, Barcode: "", Reason: "missing Barcode"},
+	}
+	if report.Rows != 1 || len(report.Rejections) != len(want) {
+		t.Fatalf("Expected 1 row and %d rejections, got %+v", len(want), report)
+	}
+	for i := range want {
+		if report.Rejections[i] != want[i] {
+			t.Errorf("Expected rejection %+v, got %+v", want[i], report.Rejections[i])
+		}
+	}
+
+	content, err := os.ReadFile(report.File)
+	if err != nil {
+		t.Fatalf("Error reading output: %v", err)
+	}
+	if want := "79927398713;Test1;;POS;;;Instrument1\n"; string(content) != want {
+		t.Errorf("Expected output %q, got %q", want, content)
+	}
+}
@@ -0,0 +1,203 @@
+package FlowG
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ResultType is the kind of result a test in the TestCatalog produces.
+type ResultType string
+
+// Available result types of a TestDefinition
+const (
+	ResultTypeAny     ResultType = ""        // Numeric or text results
+	ResultTypeNumeric ResultType = "numeric" // Only numeric results, in SampleStruct.Result
+	ResultTypeText    ResultType = "text"    // Only text results, in SampleStruct.ResultText
+)
+
+// Available behaviours for the 'catalogPolicy' config key
+const (
+	CatalogReject = "reject" // Leave samples that do not conform to the catalog out of the output (default)
+	CatalogFlag   = "flag"   // Write samples that do not conform to the catalog, reporting the problems as warnings
+)
+
+// catalogListSeparator separates the items of a list in a CSV test catalog.
+const catalogListSeparator = "|"
+
+// TestDefinition describes a valid TEST_ID and the results GLIMS accepts for it.
+type TestDefinition struct {
+	TestID       string     `json:"testId"`
+	ResultType   ResultType `json:"resultType,omitempty"`
+	Min          *float64   `json:"min,omitempty"`          // Lowest allowed numeric result, nil for no limit
+	Max          *float64   `json:"max,omitempty"`          // Highest allowed numeric result, nil for no limit
+	Required     []Column   `json:"required,omitempty"`     // Columns that may not be empty, such as UNIT
+	AllowedTexts []string   `json:"allowedTexts,omitempty"` // Allowed text results, empty allows any text
+}
+
+// TestCatalog lists the valid tests, used by GlimsOutput to validate samples before writing them, see the
+// 'testCatalog' config key.
+type TestCatalog struct {
+	tests map[string]TestDefinition
+}
+
+// NewTestCatalog creates a TestCatalog from a list of definitions, validating each definition.
+func NewTestCatalog(definitions []TestDefinition) (*TestCatalog, error) {
+	catalog := &TestCatalog{tests: make(map[string]TestDefinition, len(definitions))}
+	for i, definition := range definitions {
+		if definition.TestID == "" {
+			return nil, fmt.Errorf("test definition %d has no testId", i+1)
+		}
+		if _, ok := catalog.tests[definition.TestID]; ok {
+			return nil, fmt.Errorf("test '%s' is defined twice", definition.TestID)
+		}
+		switch definition.ResultType {
+		case ResultTypeAny, ResultTypeNumeric, ResultTypeText:
+		default:
+			return nil, fmt.Errorf("test '%s' has an unknown result type '%s'", definition.TestID, definition.ResultType)
+		}
+		if definition.Min != nil && definition.Max != nil && *definition.Min > *definition.Max {
+			return nil, fmt.Errorf("test '%s' has a minimum above its maximum", definition.TestID)
+		}
+		for _, column := range definition.Required {
+			if !column.known() {
+				return nil, fmt.Errorf("test '%s' requires an unknown column '%s'", definition.TestID, column)
+			}
+		}
+		catalog.tests[definition.TestID] = definition
+	}
+	return catalog, nil
+}
+
+// LoadTestCatalog reads a TestCatalog from a JSON or CSV file, depending on its extension. A JSON catalog is a list
+// of TestDefinition objects. A CSV catalog has a header with the columns testId, resultType, min, max, required and
+// allowedTexts, separated by semicolons or commas. Only testId is mandatory, and lists are separated by '|'.
+func LoadTestCatalog(path string) (*TestCatalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var definitions []TestDefinition
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(file).Decode(&definitions)
+	case ".csv", ".txt":
+		definitions, err = readCatalogCSV(file)
+	default:
+		return nil, fmt.Errorf("unsupported test catalog format '%s', use .json or .csv", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read test catalog '%s': %w", path, err)
+	}
+
+	catalog, err := NewTestCatalog(definitions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid test catalog '%s': %w", path, err)
+	}
+	return catalog, nil
+}
+
+// readCatalogCSV reads the test definitions of a CSV catalog.
+func readCatalogCSV(r io.Reader) ([]TestDefinition, error) {
+	table, err := readCSVTable(r, "testId")
+	if err != nil {
+		return nil, err
+	}
+	list := func(value string) []string {
+		if value == "" {
+			return nil
+		}
+		items := strings.Split(value, catalogListSeparator)
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		return items
+	}
+	number := func(line int, name, value string) (*float64, error) {
+		if value == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s '%s'", line, name, value)
+		}
+		return &v, nil
+	}
+
+	definitions := make([]TestDefinition, 0, len(table.rows))
+	for i, row := range table.rows {
+		line := i + 2
+		definition := TestDefinition{
+			TestID:       table.field(row, "testId"),
+			ResultType:   ResultType(table.field(row, "resultType")),
+			AllowedTexts: list(table.field(row, "allowedTexts")),
+		}
+		for _, column := range list(table.field(row, "required")) {
+			definition.Required = append(definition.Required, Column(column))
+		}
+		if definition.Min, err = number(line, "min", table.field(row, "min")); err != nil {
+			return nil, err
+		}
+		if definition.Max, err = number(line, "max", table.field(row, "max")); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
+
+// Test returns the definition of testID, and whether it is part of the catalog.
+func (c *TestCatalog) Test(testID string) (TestDefinition, bool) {
+	definition, ok := c.tests[testID]
+	return definition, ok
+}
+
+// validateCatalog returns the problems that prevent GLIMS from accepting sample according to config.testCatalog.
+func (p *Pipeline) validateCatalog(sample SampleStruct) []string {
+	catalog := p.config.testCatalog
+	if catalog == nil {
+		return nil
+	}
+
+	definition, ok := catalog.Test(sample.TestName)
+	if !ok {
+		return []string{fmt.Sprintf("unknown test '%s'", sample.TestName)}
+	}
+
+	var problems []string
+	switch {
+	case definition.ResultType == ResultTypeNumeric && sample.ResultText != "":
+		problems = append(problems, fmt.Sprintf("test '%s' requires a numeric result, got '%s'", sample.TestName,
+			sample.ResultText))
+	case definition.ResultType == ResultTypeText && sample.Result != nil:
+		problems = append(problems, fmt.Sprintf("test '%s' requires a text result, got %v", sample.TestName,
+			*sample.Result))
+	}
+	if sample.Result != nil {
+		if definition.Min != nil && *sample.Result < *definition.Min {
+			problems = append(problems, fmt.Sprintf("result %v is below the minimum %v of test '%s'", *sample.Result,
+				*definition.Min, sample.TestName))
+		}
+		if definition.Max != nil && *sample.Result > *definition.Max {
+			problems = append(problems, fmt.Sprintf("result %v is above the maximum %v of test '%s'", *sample.Result,
+				*definition.Max, sample.TestName))
+		}
+	}
+	if sample.ResultText != "" && len(definition.AllowedTexts) > 0 && !slices.Contains(definition.AllowedTexts, sample.ResultText) {
+		problems = append(problems, fmt.Sprintf("result '%s' is not allowed for test '%s', use %s", sample.ResultText,
+			sample.TestName, strings.Join(definition.AllowedTexts, ", ")))
+	}
+	for _, column := range definition.Required {
+		if p.columnValue(sample, column) == "" {
+			problems = append(problems, fmt.Sprintf("test '%s' requires %s", sample.TestName, column))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,145 @@
+package FlowG
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadTestCatalog(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		content  string
+		wantErr  string
+		wantTest TestDefinition
+	}{
+		{"CSV with semicolons", "catalog.csv", "testId;resultType;min;max;required;allowedTexts\nSARS-CoV-2;text;;;;POS|NEG\nHBV-DNA;numeric;0;1e9;UNIT|COMMENT;\n", "",
+			TestDefinition{TestID: "HBV-DNA", ResultType: ResultTypeNumeric, Min: ptrFloat64(0), Max: ptrFloat64(1e9), Required: []Column{ColumnUnit, ColumnComment}}},
+		{"CSV with commas", "catalog.csv", "testId, max\nHBV-DNA, 100\n", "",
+			TestDefinition{TestID: "HBV-DNA", Max: ptrFloat64(100)}},
+		{"JSON", "catalog.json", `[{"testId": "HBV-DNA", "resultType": "numeric", "required": ["UNIT"]}]`, "",
+			TestDefinition{TestID: "HBV-DNA", ResultType: ResultTypeNumeric, Required: []Column{ColumnUnit}}},
+		{"CSV without testId", "catalog.csv", "test;max\nHBV-DNA;100\n", "missing testId column", TestDefinition{}},
+		{"CSV with invalid number", "catalog.csv", "testId;min\nHBV-DNA;low\n", "line 2: invalid min 'low'", TestDefinition{}},
+		{"Duplicate test", "catalog.json", `[{"testId": "HBV-DNA"}, {"testId": "HBV-DNA"}]`, "test 'HBV-DNA' is defined twice", TestDefinition{}},
+		{"Unknown required column", "catalog.json", `[{"testId": "HBV-DNA", "required": ["REMARK"]}]`, "requires an unknown column 'REMARK'", TestDefinition{}},
+		{"Unsupported format", "catalog.xml", "<tests/>", "unsupported test catalog format '.xml'", TestDefinition{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), c.file)
+			if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatalf("Error creating catalog: %v", err)
+			}
+
+			catalog, err := LoadTestCatalog(path)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTestCatalog returned error: %v", err)
+			}
+			definition, ok := catalog.Test(c.wantTest.TestID)
+			if !ok || !reflect.DeepEqual(definition, c.wantTest) {
+				t.Errorf("Expected definition %+v, got %+v", c.wantTest, definition)
+			}
+		})
+	}
+}
+
+func TestValidateCatalog(t *testing.T) {
+	catalog, err := NewTestCatalog([]TestDefinition{
+		{TestID: "SARS-CoV-2", ResultType: ResultTypeText, AllowedTexts: []string{"POS", "NEG"}},
+		{TestID: "HBV-DNA", ResultType: ResultTypeNumeric, Min: ptrFloat64(0), Max: ptrFloat64(1000), Required: []Column{ColumnUnit}},
+	})
+	if err != nil {
+		t.Fatalf("NewTestCatalog returned error: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		sample       SampleStruct
+		wantProblems []string
+	}{
+		{"Valid text result", SampleStruct{TestName: "SARS-CoV-2", ResultText: "POS"}, nil},
+		{"Valid numeric result", SampleStruct{TestName: "HBV-DNA", Result: ptrFloat64(500), Unit: "IU/mL"}, nil},
+		{"Unknown test", SampleStruct{TestName: "SARS-CoV2", ResultText: "POS"}, []string{"unknown test 'SARS-CoV2'"}},
+		{"Text not allowed", SampleStruct{TestName: "SARS-CoV-2", ResultText: "pos"}, []string{"result 'pos' is not allowed for test 'SARS-CoV-2', use POS, NEG"}},
+		{"Numeric result for text test", SampleStruct{TestName: "SARS-CoV-2", Result: ptrFloat64(1)}, []string{"test 'SARS-CoV-2' requires a text result, got 1"}},
+		{"Out of range and missing unit", SampleStruct{TestName: "HBV-DNA", Result: ptrFloat64(1500)},
+			[]string{"result 1500 is above the maximum 1000 of test 'HBV-DNA'", "test 'HBV-DNA' requires UNIT"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{testCatalog: catalog}
+			if problems := defaultPipeline().validateCatalog(c.sample); !reflect.DeepEqual(problems, c.wantProblems) {
+				t.Errorf("Expected problems %q, got %q", c.wantProblems, problems)
+			}
+		})
+	}
+}
+
+func TestGlimsOutputCatalogPolicy(t *testing.T) {
+	catalog, err := NewTestCatalog([]TestDefinition{{TestID: "SARS-CoV-2", ResultType: ResultTypeText}})
+	if err != nil {
+		t.Fatalf("NewTestCatalog returned error: %v", err)
+	}
+	samples := []SampleStruct{
+		{Barcode: "Sample1", TestName: "SARS-CoV-2", ResultText: "POS", InstrumentID: "Instrument1"},
+		{Barcode: "Sample2", TestName: "SARS-CoV2", ResultText: "NEG", InstrumentID: "Instrument1"},
+	}
+	cases := []struct {
+		name           string
+		policy         string
+		wantRows       int
+		wantRejections int
+		wantWarnings   int
+	}{
+		{"Reject", "", 1, 1, 0},
+		{"Flag", CatalogFlag, 2, 0, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logLvl:       CRITICAL,
+
+				testCatalog:   catalog,
+				catalogPolicy: c.policy,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			report, err := GlimsOutputReport("output", samples)
+			if err != nil && !errors.Is(err, ErrNoValidSamples) {
+				t.Fatalf("GlimsOutputReport returned error: %v", err)
+			}
+			if report.Rows != c.wantRows || len(report.Rejections) != c.wantRejections || len(report.Warnings) != c.wantWarnings {
+				t.Errorf("Expected %d rows, %d rejections and %d warnings, got %+v", c.wantRows, c.wantRejections, c.wantWarnings, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package FlowG
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Column is a column of the FlowG record, see the 'outputColumns' config key.
+type Column string
+
+// Available columns of the FlowG record
+const (
+	ColumnSpecimenID        Column = "SPECIMEN_ID"        // SampleStruct.Barcode
+	ColumnTestID            Column = "TEST_ID"            // SampleStruct.TestName
+	ColumnIsolationSequence Column = "ISOLATION_SEQUENCE" // SampleStruct.IsolationSequence
+	ColumnResult            Column = "RESULT"             // SampleStruct.Result, ResultComparator or ResultText
+	ColumnResultINT         Column = "RSLTTYPE_INT"       // SampleStruct.ResultINT
+	ColumnResultCT          Column = "RSLTTYPE_CT"        // SampleStruct.ResultCT
+	ColumnInstrumentID      Column = "INSTRUMENT_ID"      // SampleStruct.InstrumentID
+	ColumnUnit              Column = "UNIT"               // SampleStruct.Unit
+	ColumnFlags             Column = "FLAGS"              // SampleStruct.Flags
+	ColumnComment           Column = "COMMENT"            // SampleStruct.Comment
+	ColumnAnalysisTime      Column = "ANALYSIS_TIME"      // SampleStruct.AnalysisTime, see 'analysisTimeLayout'
+	ColumnOperatorID        Column = "OPERATOR_ID"        // SampleStruct.OperatorID
+)
+
+// DefaultColumns is the standard seven column FlowG record, used when the 'outputColumns' config key is not set.
+var DefaultColumns = []Column{
+	ColumnSpecimenID,
+	ColumnTestID,
+	ColumnIsolationSequence,
+	ColumnResult,
+	ColumnResultINT,
+	ColumnResultCT,
+	ColumnInstrumentID,
+}
+
+// defaultAnalysisTimeLayout is used for the ANALYSIS_TIME column when the 'analysisTimeLayout' config key is not set
+const defaultAnalysisTimeLayout = "20060102150405"
+
+// known reports whether c is one of the available columns.
+func (c Column) known() bool {
+	switch c {
+	case ColumnSpecimenID, ColumnTestID, ColumnIsolationSequence, ColumnResult, ColumnResultINT, ColumnResultCT,
+		ColumnInstrumentID, ColumnUnit, ColumnFlags, ColumnComment, ColumnAnalysisTime, ColumnOperatorID:
+		return true
+	}
+	return false
+}
+
+// validateColumns checks that columns is a non-empty list of known columns without duplicates.
+func validateColumns(columns []Column) error {
+	if len(columns) == 0 {
+		return errors.New("outputColumns requires at least one column")
+	}
+	seen := make(map[Column]bool, len(columns))
+	for _, column := range columns {
+		if !column.known() {
+			return fmt.Errorf("outputColumns contains an unknown column '%s'", column)
+		}
+		if seen[column] {
+			return fmt.Errorf("outputColumns contains column '%s' twice", column)
+		}
+		seen[column] = true
+	}
+	return nil
+}
+
+// columns returns the configured column layout of the FlowG record.
+func (p *Pipeline) columns() []Column {
+	if len(p.config.outputColumns) == 0 {
+		return DefaultColumns
+	}
+	return p.config.outputColumns
+}
+
+// columnValue returns the value of column for sample.
+func (p *Pipeline) columnValue(sample SampleStruct, column Column) string {
+	switch column {
+	case ColumnSpecimenID:
+		return sample.Barcode
+	case ColumnTestID:
+		return sample.TestName
+	case ColumnIsolationSequence:
+		return sample.IsolationSequence
+	case ColumnResult:
+		return p.resultColumn(sample)
+	case ColumnResultINT:
+		return p.formatResult(sample.TestName, sample.ResultINT)
+	case ColumnResultCT:
+		return p.formatResult(sample.TestName, sample.ResultCT)
+	case ColumnInstrumentID:
+		return sample.InstrumentID
+	case ColumnUnit:
+		return sample.Unit
+	case ColumnFlags:
+		return sample.Flags
+	case ColumnComment:
+		return sample.Comment
+	case ColumnAnalysisTime:
+		if sample.AnalysisTime.IsZero() {
+			return ""
+		}
+		layout := p.config.analysisTimeLayout
+		if layout == "" {
+			layout = defaultAnalysisTimeLayout
+		}
+		return sample.AnalysisTime.Format(layout)
+	case ColumnOperatorID:
+		return sample.OperatorID
+	}
+	return ""
+}
+
+// record returns the FlowG record of sample in the configured column layout, or why the free text fields of the
+// sample cannot be written.
+func (p *Pipeline) record(sample SampleStruct) ([]string, string) {
+	columns := p.columns()
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		value := p.columnValue(sample, column)
+		switch column {
+		case ColumnUnit, ColumnFlags, ColumnComment, ColumnOperatorID:
+			if strings.ContainsAny(value, ";\r\n\"") {
+				return nil, fmt.Sprintf("%s '%s' contains a separator, quote or line break", column, value)
+			}
+		}
+		record[i] = value
+	}
+	return record, ""
+}
@@ -0,0 +1,57 @@
+package FlowG
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	sample := SampleStruct{
+		Barcode:           "Sample1",
+		TestName:          "SARS-CoV-2",
+		IsolationSequence: "1",
+		Result:            ptrFloat64(1500),
+		ResultCT:          ptrFloat64(27.456),
+		InstrumentID:      "Instrument1",
+		Unit:              "copies/mL",
+		Flags:             "H",
+		Comment:           "Repeated after clot",
+		AnalysisTime:      time.Date(2026, 10, 17, 9, 30, 5, 0, time.Local),
+		OperatorID:        "JD",
+	}
+	cases := []struct {
+		name        string
+		columns     []Column
+		timeLayout  string
+		sample      SampleStruct
+		wantRecord  []string
+		wantProblem string
+	}{
+		{"Default layout", nil, "", sample,
+			[]string{"Sample1", "SARS-CoV-2", "1", "1500.00", "", "27.46", "Instrument1"}, ""},
+		{"Extended layout", []Column{ColumnSpecimenID, ColumnTestID, ColumnResult, ColumnUnit, ColumnFlags, ColumnComment, ColumnAnalysisTime, ColumnOperatorID}, "", sample,
+			[]string{"Sample1", "SARS-CoV-2", "1500.00", "copies/mL", "H", "Repeated after clot", "20261017093005", "JD"}, ""},
+		{"Reordered layout with time layout", []Column{ColumnAnalysisTime, ColumnTestID, ColumnSpecimenID}, "2006-01-02 15:04", sample,
+			[]string{"2026-10-17 09:30", "SARS-CoV-2", "Sample1"}, ""},
+		{"Missing analysis time", []Column{ColumnSpecimenID, ColumnAnalysisTime}, "", SampleStruct{Barcode: "Sample1"},
+			[]string{"Sample1", ""}, ""},
+		{"Comment with separator", []Column{ColumnSpecimenID, ColumnComment}, "", SampleStruct{Barcode: "Sample1", Comment: "a;b"},
+			nil, "COMMENT 'a;b' contains a separator, quote or line break"},
+		{"Comment with separator outside layout", nil, "", SampleStruct{Barcode: "Sample1", Comment: "a;b"},
+			[]string{"Sample1", "", "", "", "", "", ""}, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{outputColumns: c.columns, analysisTimeLayout: c.timeLayout}
+			record, problem := defaultPipeline().record(c.sample)
+			if problem != c.wantProblem {
+				t.Errorf("Expected problem %q, got %q", c.wantProblem, problem)
+			}
+			if !reflect.DeepEqual(record, c.wantRecord) {
+				t.Errorf("Expected record %q, got %q", c.wantRecord, record)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 )
 
 type configStruct struct {
@@ -14,13 +15,62 @@ type configStruct struct {
 	logDir       string
 	logPrefix    string
 	logLvl       uint8
+
+	shutdownTimeout     time.Duration
+	stableQuietPeriod   time.Duration
+	stablePolls         int
+	stableMaxWait       time.Duration
+	stableExclusiveOpen bool
+	watcherBackend      string
+	pollInterval        time.Duration
+	maxWorkers          int
+	queueSize           int
+	queueFullPolicy     string
+	instrumentFunc      func(string) string
+	serializeInstrument bool
+	includePatterns     []filePattern
+	excludePatterns     []filePattern
+	recursive           bool
+	flattenSubdirs      bool
+	fileHook            func(FileEvent, error)
+	callbackTimeout     time.Duration
+	maxAttempts         int
+	retryBackoff        time.Duration
+	retryMaxBackoff     time.Duration
+	ledger              *ledger
+	duplicatePolicy     string
+
+	archiveLayout        string
+	archiveCompressAfter time.Duration
+	archiveRetention     time.Duration
+
+	numberFormats      map[string]NumberFormat
+	outputColumns      []Column
+	analysisTimeLayout string
+	testCatalog        *TestCatalog
+	catalogPolicy      string
+	testMapping        *TestMapping
+	barcodeValidator   *BarcodeValidator
 }
 
+// config holds the configuration of the default pipeline, used by the package-level functions
 var config = &configStruct{}
 
-// SetConfig sets the specified configuration key to the provided value, performing type-checking and validation.
-// Available keys are: 'glimsDir', 'processedDir', 'errorDir', 'logDir', 'logLvl'.
+// SetConfig sets the specified configuration key of the default pipeline to the provided value, see
+// Pipeline.SetConfig.
 func SetConfig(key string, value interface{}) error {
+	return defaultPipeline().SetConfig(key, value)
+}
+
+// SetConfig sets the specified configuration key to the provided value, performing type-checking and validation.
+// Available keys are: 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', 'logLvl',
+// 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend',
+// 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument',
+// 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts',
+// 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter',
+// 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy',
+// 'testMapping', 'barcodeValidator'.
+func (p *Pipeline) SetConfig(key string, value interface{}) error {
 	isDir := false
 
 	switch key {
@@ -29,7 +79,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("glimsDir requires a string value")
 		}
-		config.glimsDir = v
+		p.config.glimsDir = v
 		isDir = true
 
 	case "importDir":
@@ -37,7 +87,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("importDir requires a string value")
 		}
-		config.importDir = v
+		p.config.importDir = v
 		isDir = true
 
 	case "processedDir":
@@ -45,7 +95,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("processedDir requires a string value")
 		}
-		config.processedDir = v
+		p.config.processedDir = v
 		isDir = true
 
 	case "errorDir":
@@ -53,7 +103,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("errorDir requires a string value")
 		}
-		config.errorDir = v
+		p.config.errorDir = v
 		isDir = true
 
 	case "logDir":
@@ -61,7 +111,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("logDir requires a string value")
 		}
-		config.logDir = v
+		p.config.logDir = v
 		isDir = true
 
 	case "logPrefix":
@@ -69,7 +119,7 @@ func SetConfig(key string, value interface{}) error {
 		if !ok {
 			return errors.New("logPrefix requires a string value")
 		}
-		config.logPrefix = v
+		p.config.logPrefix = v
 
 	case "logLvl":
 		v, ok := value.(uint8)
@@ -87,10 +137,302 @@ func SetConfig(key string, value interface{}) error {
 		if _, exists := levelNames[v]; !exists {
 			return errors.New("logLvl requires a valid log level, use 0 (DEBUG), 1 (INFO), 2 (WARN), 3 (ERROR), or 4 (CRITICAL)")
 		}
-		config.logLvl = v
+		p.config.logLvl = v
+
+	case "shutdownTimeout":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return errors.New("shutdownTimeout requires a time.Duration value")
+		}
+		if v < 0 {
+			return errors.New("shutdownTimeout cannot be negative")
+		}
+		p.config.shutdownTimeout = v
+
+	case "stableQuietPeriod":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return errors.New("stableQuietPeriod requires a time.Duration value")
+		}
+		if v <= 0 {
+			return errors.New("stableQuietPeriod must be positive")
+		}
+		p.config.stableQuietPeriod = v
+
+	case "stablePolls":
+		v, ok := value.(int)
+		if !ok {
+			return errors.New("stablePolls requires an integer value")
+		}
+		if v < 1 {
+			return errors.New("stablePolls must be at least 1")
+		}
+		p.config.stablePolls = v
+
+	case "stableMaxWait":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return errors.New("stableMaxWait requires a time.Duration value")
+		}
+		if v <= 0 {
+			return errors.New("stableMaxWait must be positive")
+		}
+		p.config.stableMaxWait = v
+
+	case "stableExclusiveOpen":
+		v, ok := value.(bool)
+		if !ok {
+			return errors.New("stableExclusiveOpen requires a boolean value")
+		}
+		p.config.stableExclusiveOpen = v
+
+	case "watcherBackend":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("watcherBackend requires a string value")
+		}
+		if v != BackendFsnotify && v != BackendPoll {
+			return fmt.Errorf("watcherBackend requires a valid backend, use '%s' or '%s'", BackendFsnotify, BackendPoll)
+		}
+		p.config.watcherBackend = v
+
+	case "pollInterval":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return errors.New("pollInterval requires a time.Duration value")
+		}
+		if v <= 0 {
+			return errors.New("pollInterval must be positive")
+		}
+		p.config.pollInterval = v
+
+	case "maxWorkers":
+		v, ok := value.(int)
+		if !ok {
+			return errors.New("maxWorkers requires an integer value")
+		}
+		if v < 0 {
+			return errors.New("maxWorkers cannot be negative")
+		}
+		p.config.maxWorkers = v
+
+	case "queueSize":
+		v, ok := value.(int)
+		if !ok {
+			return errors.New("queueSize requires an integer value")
+		}
+		if v < 0 {
+			return errors.New("queueSize cannot be negative")
+		}
+		p.config.queueSize = v
+
+	case "queueFullPolicy":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("queueFullPolicy requires a string value")
+		}
+		if v != QueueFullBlock && v != QueueFullSkip {
+			return fmt.Errorf("queueFullPolicy requires a valid policy, use '%s' or '%s'", QueueFullBlock, QueueFullSkip)
+		}
+		p.config.queueFullPolicy = v
+
+	case "instrumentFunc":
+		v, ok := value.(func(string) string)
+		if !ok {
+			return errors.New("instrumentFunc requires a func(string) string value")
+		}
+		p.config.instrumentFunc = v
+
+	case "serializeInstrument":
+		v, ok := value.(bool)
+		if !ok {
+			return errors.New("serializeInstrument requires a boolean value")
+		}
+		p.config.serializeInstrument = v
+
+	case "includePatterns", "excludePatterns":
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("%s requires a []string value", key)
+		}
+		patterns, err := compilePatterns(v)
+		if err != nil {
+			return fmt.Errorf("%s contains an %w", key, err)
+		}
+		if key == "includePatterns" {
+			p.config.includePatterns = patterns
+		} else {
+			p.config.excludePatterns = patterns
+		}
+
+	case "recursive":
+		v, ok := value.(bool)
+		if !ok {
+			return errors.New("recursive requires a boolean value")
+		}
+		p.config.recursive = v
+
+	case "flattenSubdirs":
+		v, ok := value.(bool)
+		if !ok {
+			return errors.New("flattenSubdirs requires a boolean value")
+		}
+		p.config.flattenSubdirs = v
+
+	case "fileHook":
+		v, ok := value.(func(FileEvent, error))
+		if !ok {
+			return errors.New("fileHook requires a func(FileEvent, error) value")
+		}
+		p.config.fileHook = v
+
+	case "callbackTimeout":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return errors.New("callbackTimeout requires a time.Duration value")
+		}
+		if v < 0 {
+			return errors.New("callbackTimeout cannot be negative")
+		}
+		p.config.callbackTimeout = v
+
+	case "maxAttempts":
+		v, ok := value.(int)
+		if !ok {
+			return errors.New("maxAttempts requires an integer value")
+		}
+		if v < 1 {
+			return errors.New("maxAttempts must be at least 1")
+		}
+		p.config.maxAttempts = v
+
+	case "retryBackoff", "retryMaxBackoff":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("%s requires a time.Duration value", key)
+		}
+		if v <= 0 {
+			return fmt.Errorf("%s must be positive", key)
+		}
+		if key == "retryBackoff" {
+			p.config.retryBackoff = v
+		} else {
+			p.config.retryMaxBackoff = v
+		}
+
+	case "ledgerDir":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("ledgerDir requires a string value")
+		}
+		if v == "" {
+			p.config.ledger = nil
+			break
+		}
+		p.config.ledger = newLedger(v)
+		isDir = true
+
+	case "duplicatePolicy":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("duplicatePolicy requires a string value")
+		}
+		if v != DuplicateSkip && v != DuplicateWarn && v != DuplicateForce {
+			return fmt.Errorf("duplicatePolicy requires a valid policy, use '%s', '%s' or '%s'", DuplicateSkip,
+				DuplicateWarn, DuplicateForce)
+		}
+		p.config.duplicatePolicy = v
+
+	case "archiveLayout":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("archiveLayout requires a string value")
+		}
+		if v != "" {
+			if err := validateArchiveLayout(v); err != nil {
+				return err
+			}
+		}
+		p.config.archiveLayout = v
+
+	case "archiveCompressAfter", "archiveRetention":
+		v, ok := value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("%s requires a time.Duration value", key)
+		}
+		if v < 0 {
+			return fmt.Errorf("%s cannot be negative", key)
+		}
+		if key == "archiveCompressAfter" {
+			p.config.archiveCompressAfter = v
+		} else {
+			p.config.archiveRetention = v
+		}
+
+	case "numberFormats":
+		v, ok := value.(map[string]NumberFormat)
+		if !ok {
+			return errors.New("numberFormats requires a map[string]NumberFormat value")
+		}
+		formats := make(map[string]NumberFormat, len(v))
+		for testName, format := range v {
+			if err := format.validate(); err != nil {
+				return fmt.Errorf("numberFormats contains an invalid format for '%s': %w", testName, err)
+			}
+			formats[testName] = format
+		}
+		p.config.numberFormats = formats
+
+	case "outputColumns":
+		v, ok := value.([]Column)
+		if !ok {
+			return errors.New("outputColumns requires a []Column value")
+		}
+		if err := validateColumns(v); err != nil {
+			return err
+		}
+		p.config.outputColumns = append([]Column(nil), v...)
+
+	case "analysisTimeLayout":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("analysisTimeLayout requires a string value")
+		}
+		p.config.analysisTimeLayout = v
+
+	case "testCatalog":
+		v, ok := value.(*TestCatalog)
+		if !ok {
+			return errors.New("testCatalog requires a *TestCatalog value")
+		}
+		p.config.testCatalog = v
+
+	case "catalogPolicy":
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("catalogPolicy requires a string value")
+		}
+		if v != CatalogReject && v != CatalogFlag {
+			return fmt.Errorf("catalogPolicy requires a valid policy, use '%s' or '%s'", CatalogReject, CatalogFlag)
+		}
+		p.config.catalogPolicy = v
+
+	case "testMapping":
+		v, ok := value.(*TestMapping)
+		if !ok {
+			return errors.New("testMapping requires a *TestMapping value")
+		}
+		p.config.testMapping = v
+
+	case "barcodeValidator":
+		v, ok := value.(*BarcodeValidator)
+		if !ok {
+			return errors.New("barcodeValidator requires a *BarcodeValidator value")
+		}
+		p.config.barcodeValidator = v
 
 	default:
-		return fmt.Errorf("unknown config key (%s): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', or 'logLvl'", key)
+		return fmt.Errorf("unknown config key (%s): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', 'logLvl', 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend', 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument', 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts', 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter', 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy', 'testMapping', or 'barcodeValidator'", key)
 	}
 
 	// Check directory existence only for path keys
@@ -103,27 +445,108 @@ func SetConfig(key string, value interface{}) error {
 	return nil
 }
 
+// GetConfig retrieves the configuration value of the default pipeline associated with the given key, see
+// Pipeline.GetConfig.
+func GetConfig(key string) (interface{}, error) {
+	return defaultPipeline().GetConfig(key)
+}
+
 // GetConfig retrieves the configuration value associated with the given key.
-// Available keys are: 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logLvl'.
+// Available keys are: 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', 'logLvl',
+// 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend',
+// 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument',
+// 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts',
+// 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter',
+// 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy',
+// 'testMapping', 'barcodeValidator'.
 // Returns the configuration value and a nil error if the key is found,
 // otherwise returns nil and an error indicating that the key is unknown.
-func GetConfig(key string) (interface{}, error) {
+func (p *Pipeline) GetConfig(key string) (interface{}, error) {
 	switch key {
 	case "glimsDir":
-		return config.glimsDir, nil
+		return p.config.glimsDir, nil
 	case "importDir":
-		return config.importDir, nil
+		return p.config.importDir, nil
 	case "processedDir":
-		return config.processedDir, nil
+		return p.config.processedDir, nil
 	case "errorDir":
-		return config.errorDir, nil
+		return p.config.errorDir, nil
 	case "logDir":
-		return config.logDir, nil
+		return p.config.logDir, nil
 	case "logPrefix":
-		return config.logPrefix, nil
+		return p.config.logPrefix, nil
 	case "logLvl":
-		return config.logLvl, nil
+		return p.config.logLvl, nil
+	case "shutdownTimeout":
+		return p.config.shutdownTimeout, nil
+	case "stableQuietPeriod":
+		return p.config.stableQuietPeriod, nil
+	case "stablePolls":
+		return p.config.stablePolls, nil
+	case "stableMaxWait":
+		return p.config.stableMaxWait, nil
+	case "stableExclusiveOpen":
+		return p.config.stableExclusiveOpen, nil
+	case "watcherBackend":
+		return p.config.watcherBackend, nil
+	case "pollInterval":
+		return p.config.pollInterval, nil
+	case "maxWorkers":
+		return p.config.maxWorkers, nil
+	case "queueSize":
+		return p.config.queueSize, nil
+	case "queueFullPolicy":
+		return p.config.queueFullPolicy, nil
+	case "instrumentFunc":
+		return p.config.instrumentFunc, nil
+	case "serializeInstrument":
+		return p.config.serializeInstrument, nil
+	case "includePatterns":
+		return patternSources(p.config.includePatterns), nil
+	case "excludePatterns":
+		return patternSources(p.config.excludePatterns), nil
+	case "recursive":
+		return p.config.recursive, nil
+	case "flattenSubdirs":
+		return p.config.flattenSubdirs, nil
+	case "fileHook":
+		return p.config.fileHook, nil
+	case "callbackTimeout":
+		return p.config.callbackTimeout, nil
+	case "maxAttempts":
+		return p.config.maxAttempts, nil
+	case "retryBackoff":
+		return p.config.retryBackoff, nil
+	case "retryMaxBackoff":
+		return p.config.retryMaxBackoff, nil
+	case "ledgerDir":
+		if p.config.ledger == nil {
+			return "", nil
+		}
+		return p.config.ledger.dir, nil
+	case "duplicatePolicy":
+		return p.config.duplicatePolicy, nil
+	case "archiveLayout":
+		return p.config.archiveLayout, nil
+	case "archiveCompressAfter":
+		return p.config.archiveCompressAfter, nil
+	case "archiveRetention":
+		return p.config.archiveRetention, nil
+	case "numberFormats":
+		return p.config.numberFormats, nil
+	case "outputColumns":
+		return p.config.outputColumns, nil
+	case "analysisTimeLayout":
+		return p.config.analysisTimeLayout, nil
+	case "testCatalog":
+		return p.config.testCatalog, nil
+	case "catalogPolicy":
+		return p.config.catalogPolicy, nil
+	case "testMapping":
+		return p.config.testMapping, nil
+	case "barcodeValidator":
+		return p.config.barcodeValidator, nil
 	default:
-		return nil, fmt.Errorf("unknown config key (%s): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', logPrefix, or 'logLvl'", key)
+		return nil, fmt.Errorf("unknown config key (%s): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', logPrefix, 'logLvl', 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend', 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument', 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts', 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter', 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy', 'testMapping', or 'barcodeValidator'", key)
 	}
 }
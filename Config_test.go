@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestSetConfig(t *testing.T) {
@@ -22,7 +23,7 @@ func TestSetConfig(t *testing.T) {
 		{"Setting logDir", "logDir", "./logDir", true, nil},
 		{"Setting logPrefix", "logPrefix", "Prefix", false, nil},
 		{"Setting logLvl", "logLvl", INFO, false, nil},
-		{"Setting wrong key", "wrongKey", "value", false, errors.New(`unknown config key (wrongKey): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', or 'logLvl'`)},
+		{"Setting wrong key", "wrongKey", "value", false, errors.New(`unknown config key (wrongKey): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', 'logPrefix', 'logLvl', 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend', 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument', 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts', 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter', 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy', 'testMapping', or 'barcodeValidator'`)},
 		{"Setting glimsDir to non-existing directory", "glimsDir", "/does/not/exist", false, errors.New("cannot find or access directory: /does/not/exist")},
 		{"Setting glimsDir to non-string value", "glimsDir", 123, false, errors.New("glimsDir requires a string value")},
 		{"Setting importDir to non-string value", "importDir", 123, false, errors.New("importDir requires a string value")},
@@ -31,6 +32,68 @@ func TestSetConfig(t *testing.T) {
 		{"Setting logDir to non-string value", "logDir", 123, false, errors.New("logDir requires a string value")},
 		{"Setting logLvl to string value", "logLvl", "non-integer", false, errors.New("logLvl requires an integer value (int or uint8)")},
 		{"Setting logLvl to int value", "logLvl", 3, false, nil},
+		{"Setting shutdownTimeout", "shutdownTimeout", 30 * time.Second, false, nil},
+		{"Setting shutdownTimeout to non-duration value", "shutdownTimeout", 30, false, errors.New("shutdownTimeout requires a time.Duration value")},
+		{"Setting shutdownTimeout to negative value", "shutdownTimeout", -time.Second, false, errors.New("shutdownTimeout cannot be negative")},
+		{"Setting stableQuietPeriod", "stableQuietPeriod", 2 * time.Second, false, nil},
+		{"Setting stableQuietPeriod to zero", "stableQuietPeriod", time.Duration(0), false, errors.New("stableQuietPeriod must be positive")},
+		{"Setting stablePolls", "stablePolls", 4, false, nil},
+		{"Setting stablePolls to zero", "stablePolls", 0, false, errors.New("stablePolls must be at least 1")},
+		{"Setting stableMaxWait", "stableMaxWait", time.Minute, false, nil},
+		{"Setting stableMaxWait to non-duration value", "stableMaxWait", "1m", false, errors.New("stableMaxWait requires a time.Duration value")},
+		{"Setting stableExclusiveOpen", "stableExclusiveOpen", true, false, nil},
+		{"Setting stableExclusiveOpen to non-boolean value", "stableExclusiveOpen", 1, false, errors.New("stableExclusiveOpen requires a boolean value")},
+		{"Setting watcherBackend", "watcherBackend", BackendPoll, false, nil},
+		{"Setting watcherBackend to unknown backend", "watcherBackend", "smb", false, errors.New("watcherBackend requires a valid backend, use 'fsnotify' or 'poll'")},
+		{"Setting pollInterval", "pollInterval", 5 * time.Second, false, nil},
+		{"Setting pollInterval to zero", "pollInterval", time.Duration(0), false, errors.New("pollInterval must be positive")},
+		{"Setting maxWorkers", "maxWorkers", 4, false, nil},
+		{"Setting maxWorkers to negative value", "maxWorkers", -1, false, errors.New("maxWorkers cannot be negative")},
+		{"Setting queueSize", "queueSize", 100, false, nil},
+		{"Setting queueSize to non-integer value", "queueSize", "100", false, errors.New("queueSize requires an integer value")},
+		{"Setting queueFullPolicy", "queueFullPolicy", QueueFullSkip, false, nil},
+		{"Setting queueFullPolicy to unknown policy", "queueFullPolicy", "drop", false, errors.New("queueFullPolicy requires a valid policy, use 'block' or 'skip'")},
+		{"Setting instrumentFunc", "instrumentFunc", func(string) string { return "" }, false, nil},
+		{"Setting instrumentFunc to wrong function type", "instrumentFunc", func(string) bool { return true }, false, errors.New("instrumentFunc requires a func(string) string value")},
+		{"Setting serializeInstrument", "serializeInstrument", true, false, nil},
+		{"Setting includePatterns", "includePatterns", []string{"*.csv", `re:^run_\d+\.xml$`}, false, nil},
+		{"Setting excludePatterns", "excludePatterns", []string{"*.tmp"}, false, nil},
+		{"Setting excludePatterns to invalid glob", "excludePatterns", []string{"[.tmp"}, false, errors.New("excludePatterns contains an invalid glob pattern '[.tmp': syntax error in pattern")},
+		{"Setting includePatterns to invalid regexp", "includePatterns", []string{"re:("}, false, errors.New("includePatterns contains an invalid regular expression '(': error parsing regexp: missing closing ): `(`")},
+		{"Setting includePatterns to non-slice value", "includePatterns", "*.csv", false, errors.New("includePatterns requires a []string value")},
+		{"Setting recursive", "recursive", true, false, nil},
+		{"Setting flattenSubdirs to non-boolean value", "flattenSubdirs", "yes", false, errors.New("flattenSubdirs requires a boolean value")},
+		{"Setting fileHook", "fileHook", func(FileEvent, error) {}, false, nil},
+		{"Setting fileHook to wrong function type", "fileHook", func(string) {}, false, errors.New("fileHook requires a func(FileEvent, error) value")},
+		{"Setting callbackTimeout", "callbackTimeout", time.Minute, false, nil},
+		{"Setting callbackTimeout to negative value", "callbackTimeout", -time.Minute, false, errors.New("callbackTimeout cannot be negative")},
+		{"Setting maxAttempts", "maxAttempts", 5, false, nil},
+		{"Setting maxAttempts to zero", "maxAttempts", 0, false, errors.New("maxAttempts must be at least 1")},
+		{"Setting retryBackoff", "retryBackoff", 10 * time.Second, false, nil},
+		{"Setting retryMaxBackoff to zero", "retryMaxBackoff", time.Duration(0), false, errors.New("retryMaxBackoff must be positive")},
+		{"Setting ledgerDir", "ledgerDir", "./ledgerDir", true, nil},
+		{"Setting ledgerDir to missing directory", "ledgerDir", "./missingDir", false, errors.New("cannot find or access directory: ./missingDir")},
+		{"Disabling ledgerDir", "ledgerDir", "", false, nil},
+		{"Setting duplicatePolicy", "duplicatePolicy", DuplicateWarn, false, nil},
+		{"Setting duplicatePolicy to invalid value", "duplicatePolicy", "ignore", false, errors.New("duplicatePolicy requires a valid policy, use 'skip', 'warn' or 'force'")},
+		{"Setting archiveLayout", "archiveLayout", "{instrument}/2006/01/02", false, nil},
+		{"Setting archiveLayout outside processedDir", "archiveLayout", "../2006", false, errors.New("archiveLayout contains an invalid path element '..'")},
+		{"Setting archiveCompressAfter", "archiveCompressAfter", 7 * 24 * time.Hour, false, nil},
+		{"Setting archiveRetention to negative value", "archiveRetention", -time.Hour, false, errors.New("archiveRetention cannot be negative")},
+		{"Setting numberFormats", "numberFormats", map[string]NumberFormat{"CT": {Decimals: 1}, "*": {Decimals: 3}}, false, nil},
+		{"Setting numberFormats with invalid format", "numberFormats", map[string]NumberFormat{"CT": {Decimals: -1}}, false, errors.New("numberFormats contains an invalid format for 'CT': decimals must be between 0 and 17")},
+		{"Setting outputColumns", "outputColumns", []Column{ColumnSpecimenID, ColumnTestID, ColumnResult, ColumnUnit}, false, nil},
+		{"Setting outputColumns with unknown column", "outputColumns", []Column{ColumnSpecimenID, "REMARK"}, false, errors.New("outputColumns contains an unknown column 'REMARK'")},
+		{"Setting outputColumns with duplicate column", "outputColumns", []Column{ColumnResult, ColumnResult}, false, errors.New("outputColumns contains column 'RESULT' twice")},
+		{"Setting analysisTimeLayout", "analysisTimeLayout", "2006-01-02T15:04:05", false, nil},
+		{"Setting testCatalog", "testCatalog", &TestCatalog{}, false, nil},
+		{"Setting testCatalog to a file name", "testCatalog", "catalog.csv", false, errors.New("testCatalog requires a *TestCatalog value")},
+		{"Setting catalogPolicy", "catalogPolicy", CatalogFlag, false, nil},
+		{"Setting catalogPolicy to invalid value", "catalogPolicy", "ignore", false, errors.New("catalogPolicy requires a valid policy, use 'reject' or 'flag'")},
+		{"Setting testMapping", "testMapping", &TestMapping{}, false, nil},
+		{"Setting testMapping to a file name", "testMapping", "mapping.csv", false, errors.New("testMapping requires a *TestMapping value")},
+		{"Setting barcodeValidator", "barcodeValidator", &BarcodeValidator{}, false, nil},
+		{"Setting barcodeValidator to a pattern", "barcodeValidator", `^\d{10}$`, false, errors.New("barcodeValidator requires a *BarcodeValidator value")},
 		{"Setting logLvl to out-of-range value", "logLvl", uint8(5), false, errors.New("logLvl requires a valid log level, use 0 (DEBUG), 1 (INFO), 2 (WARN), 3 (ERROR), or 4 (CRITICAL)")},
 	}
 
@@ -70,7 +133,11 @@ func TestGetConfig(t *testing.T) {
 		{"Getting logDir", "logDir", "./logDir", nil},
 		{"Getting logPrefix", "logPrefix", "Prefix", nil},
 		{"Getting logLvl", "logLvl", INFO, nil},
-		{"Getting wrong key", "wrongKey", nil, errors.New(`unknown config key (wrongKey): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', logPrefix, or 'logLvl'`)},
+		{"Getting shutdownTimeout", "shutdownTimeout", 30 * time.Second, nil},
+		{"Getting stablePolls", "stablePolls", 4, nil},
+		{"Getting watcherBackend", "watcherBackend", BackendPoll, nil},
+		{"Getting maxWorkers", "maxWorkers", 4, nil},
+		{"Getting wrong key", "wrongKey", nil, errors.New(`unknown config key (wrongKey): use 'glimsDir', 'importDir', 'processedDir', 'errorDir', 'logDir', logPrefix, 'logLvl', 'shutdownTimeout', 'stableQuietPeriod', 'stablePolls', 'stableMaxWait', 'stableExclusiveOpen', 'watcherBackend', 'pollInterval', 'maxWorkers', 'queueSize', 'queueFullPolicy', 'instrumentFunc', 'serializeInstrument', 'includePatterns', 'excludePatterns', 'recursive', 'flattenSubdirs', 'fileHook', 'callbackTimeout', 'maxAttempts', 'retryBackoff', 'retryMaxBackoff', 'ledgerDir', 'duplicatePolicy', 'archiveLayout', 'archiveCompressAfter', 'archiveRetention', 'numberFormats', 'outputColumns', 'analysisTimeLayout', 'testCatalog', 'catalogPolicy', 'testMapping', or 'barcodeValidator'`)},
 	}
 
 	config = &configStruct{
@@ -80,6 +147,11 @@ func TestGetConfig(t *testing.T) {
 		logDir:       "./logDir",
 		logPrefix:    "Prefix",
 		logLvl:       INFO,
+
+		shutdownTimeout: 30 * time.Second,
+		stablePolls:     4,
+		watcherBackend:  BackendPoll,
+		maxWorkers:      4,
 	}
 
 	for _, c := range cases {
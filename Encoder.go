@@ -0,0 +1,112 @@
+package FlowG
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encoder writes samples as FlowG records to an io.Writer, applying the same mapping, validation and formatting as
+// GlimsOutput. Use it to stream FlowG to another destination than glimsDir, such as an upload or a buffer.
+type Encoder struct {
+	p      *Pipeline
+	writer *csv.Writer
+	report OutputReport
+	hashes []string
+	index  int
+}
+
+// NewEncoder returns an Encoder that writes to w with the configuration of the default pipeline, see
+// Pipeline.NewEncoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return defaultPipeline().NewEncoder(w)
+}
+
+// NewEncoder returns an Encoder that writes to w with the configuration of the pipeline. Records are buffered, call
+// Flush once all samples are encoded.
+func (p *Pipeline) NewEncoder(w io.Writer) *Encoder {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	return &Encoder{p: p, writer: writer}
+}
+
+// Encode maps, validates and writes samples. Samples that cannot be written are not an error, but are reported in
+// Report, with their index counted over all calls of Encode. Returns an error if writing a record failed.
+func (e *Encoder) Encode(samples ...SampleStruct) error {
+	p := e.p
+	for _, sample := range p.applyMapping(samples, &e.report) {
+		index := e.index
+		e.index++
+
+		p.Logging(fmt.Sprintf("GlimsOutput - Processing sample: %v", sample), DEBUG)
+		barcode, err := p.normalizeBarcode(sample)
+		if err != nil {
+			e.report.reject(p, index, sample.Barcode, err.Error())
+			continue
+		}
+		sample.Barcode = barcode
+		sample = normalizeResult(sample)
+		if missing := missingFields(sample); len(missing) > 0 {
+			e.report.reject(p, index, sample.Barcode, "missing "+strings.Join(missing, ", "))
+			continue
+		}
+		if problem := resultProblem(sample); problem != "" {
+			e.report.reject(p, index, sample.Barcode, problem)
+			continue
+		}
+		record, problem := p.record(sample)
+		if problem != "" {
+			e.report.reject(p, index, sample.Barcode, problem)
+			continue
+		}
+		if problems := p.validateCatalog(sample); len(problems) > 0 {
+			if p.config.catalogPolicy != CatalogFlag {
+				e.report.reject(p, index, sample.Barcode, strings.Join(problems, "; "))
+				continue
+			}
+			e.report.warn(p, fmt.Sprintf("Sample %d (barcode '%s') does not conform to the test catalog: %s", index,
+				sample.Barcode, strings.Join(problems, "; ")))
+		}
+		hash, duplicate := p.checkDuplicateRecord(record)
+		if duplicate {
+			if p.duplicatePolicy() == DuplicateSkip {
+				e.report.Duplicates++
+				e.report.warn(p, fmt.Sprintf("Result %s/%s for sample '%s' was delivered before, skipping it",
+					sample.TestName, sample.IsolationSequence, sample.Barcode))
+				continue
+			}
+			e.report.warn(p, fmt.Sprintf("Result %s/%s for sample '%s' was delivered before, delivering it again",
+				sample.TestName, sample.IsolationSequence, sample.Barcode))
+		}
+
+		if err = e.writer.Write(record); err != nil {
+			return err
+		}
+
+		e.report.Rows++
+		if hash != "" {
+			e.hashes = append(e.hashes, hash)
+		}
+		p.Logging(fmt.Sprintf("GlimsOutput - Sample '%s' was processed correcly", sample.Barcode), DEBUG)
+	}
+	return nil
+}
+
+// Flush writes the buffered records to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// Report returns the rows written and the samples rejected or skipped so far. Its File is always empty.
+func (e *Encoder) Report() OutputReport {
+	return e.report
+}
+
+// Commit records the written results in the ledger as delivered under name, so they are detected as duplicates
+// later, see the 'ledgerDir' config key. Call it only once the output is delivered.
+func (e *Encoder) Commit(name string) {
+	e.p.recordSamples(name, e.hashes)
+	e.hashes = nil
+}
@@ -0,0 +1,73 @@
+package FlowG
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestEncoder(t *testing.T) {
+	config = &configStruct{logDir: t.TempDir(), logLvl: CRITICAL, ledger: newLedger(t.TempDir())}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	samples := []SampleStruct{
+		{Barcode: "Sample1", TestName: "Test1", Result: ptrFloat64(27.456), InstrumentID: "Instrument1"},
+		{Barcode: "Sample2", TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"},
+	}
+	if err := encoder.Encode(samples...); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if err := encoder.Encode(SampleStruct{TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"}); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("Flush returned error: %v", err)
+	}
+
+	want := "Sample1;Test1;;27.46;;;Instrument1\nSample2;Test1;;NEG;;;Instrument1\n"
+	if buf.String() != want {
+		t.Errorf("Expected output %q, got %q", want, buf.String())
+	}
+	report := encoder.Report()
+	wantRejection := Rejection{Index: 2, Reason: "missing Barcode"}
+	if report.Rows != 2 || len(report.Rejections) != 1 || report.Rejections[0] != wantRejection || report.File != "" {
+		t.Errorf("Expected 2 rows and rejection %+v, got %+v", wantRejection, report)
+	}
+
+	// Results are only detected as duplicates once they are committed
+	again := NewEncoder(&bytes.Buffer{})
+	if err := again.Encode(samples...); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if report := again.Report(); report.Duplicates != 0 {
+		t.Errorf("Expected no duplicates before Commit, got %d", report.Duplicates)
+	}
+	encoder.Commit("upload-1")
+	again = NewEncoder(&bytes.Buffer{})
+	if err := again.Encode(samples...); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if report := again.Report(); report.Duplicates != 2 || report.Rows != 0 {
+		t.Errorf("Expected 2 duplicates after Commit, got %+v", report)
+	}
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	config = &configStruct{logDir: t.TempDir(), logLvl: CRITICAL}
+
+	encoder := NewEncoder(failingWriter{})
+	if err := encoder.Encode(SampleStruct{Barcode: "Sample1", TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"}); err != nil {
+		t.Fatalf("Encode returned error: %v", err)
+	}
+	if err := encoder.Flush(); err == nil {
+		t.Error("Expected Flush to return the error of the writer")
+	}
+}
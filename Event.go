@@ -0,0 +1,127 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// ErrCallbackFailed is the error reported for files whose func(string) bool callback returned false.
+var ErrCallbackFailed = errors.New("callback reported failure")
+
+// ErrCallbackPanic is the error reported for files whose callback panicked.
+var ErrCallbackPanic = errors.New("callback panicked")
+
+// ErrCallbackTimeout is the error reported for files whose callback did not return within the 'callbackTimeout'.
+var ErrCallbackTimeout = errors.New("callback timed out")
+
+// FileEvent describes a file that is handed to an EventHandler.
+type FileEvent struct {
+	Path       string    // Path of the file in importDir
+	RelPath    string    // Path of the file relative to importDir
+	Size       int64     // Size of the completely written file in bytes
+	Detected   time.Time // Moment the file was detected by the watch
+	Attempt    int       // Number of the processing attempt, starting at 1
+	Instrument string    // Instrument of the file, as returned by the 'instrumentFunc' config key
+	Pipeline   string    // Name of the pipeline processing the file
+
+	ctx       context.Context
+	abandoned *callbackTracker // Tracks the handlers that keep running after a timeout, nil outside a watch
+	idle      func(func())     // Runs a wait without holding a worker of the watch, nil outside a watch
+}
+
+// Context returns the context of the watch that detected the file. It is cancelled when the watch stops.
+func (e FileEvent) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// EventHandler processes the file described by a FileEvent. Returning nil moves the file to processedDir, returning
+// an error moves it to errorDir with the error text written to a sidecar file next to it.
+type EventHandler func(FileEvent) error
+
+// BoolHandler adapts a func(string) bool callback, as used by FileWatch, to an EventHandler. A callback returning
+// false results in ErrCallbackFailed. The callback has no context, so it cannot be cancelled by the 'callbackTimeout'
+// config key or by stopping the watch.
+func BoolHandler(callback func(string) bool) EventHandler {
+	return func(event FileEvent) error {
+		if !callback(event.Path) {
+			return ErrCallbackFailed
+		}
+		return nil
+	}
+}
+
+// callbackTimeoutError is returned by runHandler when the handler did not return within config.callbackTimeout.
+type callbackTimeoutError struct {
+	timeout time.Duration
+	late    <-chan error // Receives the result of the handler once it returns
+}
+
+func (e *callbackTimeoutError) Error() string {
+	return fmt.Sprintf("%v after %s", ErrCallbackTimeout, e.timeout)
+}
+
+func (e *callbackTimeoutError) Unwrap() error {
+	return ErrCallbackTimeout
+}
+
+// runHandler executes handler for event, recovering from panics and enforcing config.callbackTimeout. A panic is
+// logged at CRITICAL level with its stack trace. On timeout the context of the event is cancelled and the file is
+// treated as failed; the handler itself keeps running in the background until it returns, and its result is
+// delivered through the returned *callbackTimeoutError.
+func (p *Pipeline) runHandler(event FileEvent, handler EventHandler) error {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if p.config.callbackTimeout > 0 {
+		ctx, cancel = context.WithTimeout(event.Context(), p.config.callbackTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(event.Context())
+	}
+	defer cancel()
+	event.ctx = ctx
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				p.Logging(fmt.Sprintf("Callback panicked while processing '%s': %v\n%s", event.Path, r, debug.Stack()), CRITICAL)
+				result <- fmt.Errorf("%w: %v", ErrCallbackPanic, r)
+			}
+		}()
+		result <- handler(event)
+	}()
+
+	if p.config.callbackTimeout == 0 {
+		return <-result
+	}
+	timer := time.NewTimer(p.config.callbackTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return &callbackTimeoutError{timeout: p.config.callbackTimeout, late: result}
+	}
+}
+
+// errorSidecarSuffix is appended to the name of a file in errorDir to get the name of its error sidecar file.
+const errorSidecarSuffix = ".error.txt"
+
+// writeErrorSidecar writes the reason a file failed to '<path>.error.txt', where path is the location of the file in
+// errorDir.
+func (p *Pipeline) writeErrorSidecar(path string, event FileEvent, reason error) {
+	content := fmt.Sprintf("File: %s\nPipeline: %s\nInstrument: %s\nAttempt: %d\nDetected: %s\nFailed: %s\nError: %v\n",
+		event.RelPath, event.Pipeline, event.Instrument, event.Attempt, event.Detected.Format("2006-01-02 15:04:05.000"),
+		time.Now().Format("2006-01-02 15:04:05.000"), reason)
+
+	err := os.WriteFile(path+errorSidecarSuffix, []byte(content), 0644)
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot write error reason for '%s': %v", path, err), WARNING)
+	}
+}
@@ -0,0 +1,277 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWatchEvents(t *testing.T) {
+	cases := []struct {
+		name        string
+		handlerErr  error
+		wantDir     string
+		wantSidecar bool
+	}{
+		{"Handler succeeds", nil, "./processed", false},
+		{"Handler fails", errors.New("unknown plate layout"), "./error", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hookCalls := make(chan error, 1)
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       WARNING,
+
+				stableQuietPeriod: 100 * time.Millisecond,
+				instrumentFunc: func(string) string {
+					return "Analyser1"
+				},
+				fileHook: func(event FileEvent, err error) {
+					hookCalls <- err
+				},
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			events := make(chan FileEvent, 1)
+			ctxErrs := make(chan error, 1)
+			handler := func(event FileEvent) error {
+				ctxErrs <- event.Context().Err()
+				events <- event
+				return c.handlerErr
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchEvents(ctx, handler)
+			}()
+
+			// Allow time for FileWatchEvents to initialize
+			time.Sleep(100 * time.Millisecond)
+			err = os.WriteFile(filepath.Join(config.importDir, "testFile.txt"), []byte("data"), 0644)
+			if err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			select {
+			case event := <-events:
+				if event.RelPath != "testFile.txt" || event.Size != 4 || event.Attempt != 1 || event.Instrument != "Analyser1" || event.Detected.IsZero() {
+					t.Errorf("Unexpected FileEvent %+v", event)
+				}
+				if <-ctxErrs != nil {
+					t.Errorf("Expected the event context to be active while handling the file")
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatalf("FileWatchEvents() got timeout, expected file detection")
+			}
+
+			select {
+			case hookErr := <-hookCalls:
+				if !errors.Is(hookErr, c.handlerErr) {
+					t.Errorf("Hook received error %v, expected %v", hookErr, c.handlerErr)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("Hook was not called")
+			}
+
+			cancel()
+			if err = <-result; err != nil {
+				t.Errorf("FileWatchEvents() returned error %v", err)
+			}
+
+			movedFiles, _ := filepath.Glob(filepath.Join(c.wantDir, "*_testFile.txt"))
+			if len(movedFiles) != 1 {
+				t.Fatalf("Expected the file in '%s'", c.wantDir)
+			}
+			sidecar, err := os.ReadFile(movedFiles[0] + ".error.txt")
+			if c.wantSidecar {
+				if err != nil {
+					t.Errorf("Expected an error sidecar file: %v", err)
+				} else if !strings.Contains(string(sidecar), "Error: "+c.handlerErr.Error()) {
+					t.Errorf("Expected the error reason in the sidecar file, got '%s'", sidecar)
+				}
+			} else if err == nil {
+				t.Errorf("Unexpected error sidecar file")
+			}
+		})
+	}
+}
+
+func TestRunHandler(t *testing.T) {
+	cases := []struct {
+		name     string
+		handler  EventHandler
+		timeout  time.Duration
+		wantErr  error
+		wantLogs int
+	}{
+		{"Handler succeeds", func(FileEvent) error { return nil }, time.Second, nil, 0},
+		{"Handler panics", func(FileEvent) error { panic("malformed file") }, 0, ErrCallbackPanic, 1},
+		{"Handler times out", func(event FileEvent) error {
+			<-event.Context().Done()
+			time.Sleep(200 * time.Millisecond) // A handler that is slow to notice the cancellation
+			return nil
+		}, 100 * time.Millisecond, ErrCallbackTimeout, 0},
+	}
+
+	config = &configStruct{
+		logDir:    "./log",
+		logPrefix: "Test",
+		logLvl:    WARNING,
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.callbackTimeout = c.timeout
+			err := os.Mkdir(config.logDir, os.ModePerm)
+			if err != nil {
+				t.Fatalf("Error creating test log folder: %v", err)
+			}
+			defer func() {
+				// Let a handler that outlived its timeout finish before the folder is removed
+				time.Sleep(300 * time.Millisecond)
+				err = os.RemoveAll(config.logDir)
+				if err != nil {
+					t.Fatalf("Error cleaning up test log folder: %v", err)
+				}
+			}()
+
+			event := FileEvent{Path: "import/testFile.txt", ctx: context.Background()}
+			err = defaultPipeline().runHandler(event, c.handler)
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("runHandler() returned error %v, wanted %v", err, c.wantErr)
+			}
+
+			logFiles, _ := os.ReadDir(config.logDir)
+			if len(logFiles) != c.wantLogs {
+				t.Fatalf("Expected %d log file(s), got %d", c.wantLogs, len(logFiles))
+			}
+			if c.wantLogs > 0 {
+				data, _ := os.ReadFile(filepath.Join(config.logDir, logFiles[0].Name()))
+				if !strings.Contains(string(data), "[CRITICAL] Callback panicked while processing 'import/testFile.txt': malformed file") || !strings.Contains(string(data), "goroutine") {
+					t.Errorf("Expected a CRITICAL log entry with stack trace, got '%s'", data)
+				}
+			}
+		})
+	}
+}
+
+func TestFileWatchCallbackTimeout(t *testing.T) {
+	cases := []struct {
+		name       string
+		lateErr    error
+		wantDir    string
+		wantInDir  int
+		wantOutDir string
+	}{
+		{"Late success moves the file to processedDir", nil, "./processed", 1, "./error"},
+		{"Late failure keeps the file in errorDir", errors.New("unknown plate layout"), "./error", 1, "./processed"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       CRITICAL,
+
+				stableQuietPeriod: 100 * time.Millisecond,
+				callbackTimeout:   100 * time.Millisecond,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			started := make(chan struct{}, 1)
+			finished := make(chan struct{})
+			handler := func(event FileEvent) error {
+				started <- struct{}{}
+				time.Sleep(500 * time.Millisecond) // Ignores the cancellation, like a func(string) bool callback
+				close(finished)
+				return c.lateErr
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchEvents(ctx, handler)
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			if err = os.WriteFile(filepath.Join(config.importDir, "testFile.txt"), []byte("data"), 0644); err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+			select {
+			case <-started:
+			case <-time.After(3 * time.Second):
+				t.Fatal("Timeout waiting for the handler to start")
+			}
+
+			// Stop the watch while the handler is still running after its timeout, which is not waited for
+			time.Sleep(200 * time.Millisecond)
+			cancel()
+			select {
+			case err = <-result:
+				if err != nil {
+					t.Errorf("FileWatchEvents() returned error %v", err)
+				}
+			case <-finished:
+				t.Fatal("FileWatchEvents() waited for the timed out handler")
+			case <-time.After(3 * time.Second):
+				t.Fatal("FileWatchEvents() did not return")
+			}
+
+			// The result of the handler is still settled once it returns
+			<-finished
+			var files []string
+			for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+				files, _ = filepath.Glob(filepath.Join(c.wantDir, "*_testFile.txt"))
+				if len(files) == c.wantInDir {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			time.Sleep(100 * time.Millisecond)
+			if len(files) != c.wantInDir {
+				t.Errorf("Expected the file in '%s', found %v", c.wantDir, files)
+			}
+			if files, _ = filepath.Glob(filepath.Join(c.wantOutDir, "*_testFile.txt*")); len(files) != 0 {
+				t.Errorf("Expected nothing in '%s', found %v", c.wantOutDir, files)
+			}
+		})
+	}
+}
@@ -1,80 +1,583 @@
 package FlowG
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/fsnotify/fsnotify"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// FileWatch continuously watches the directory specified in config.glimsDir for new file creation events.
-// When a new file is detected, it waits for 1 second before executing the provided callback function with
-// the file path as an argument. If the callback returns true, the file is moved to the processed directory;
-// otherwise, it is moved to the error directory. Logging is performed for critical errors during the process.
+// archiveTimestampLayout is the layout of the timestamp FileMove prefixes to archived files, without the dot
+const archiveTimestampLayout = "20060102150405.000"
+
+// ErrShutdownTimeout is returned by FileWatchContext when callbacks are still running after the configured
+// shutdownTimeout has passed.
+var ErrShutdownTimeout = errors.New("shutdown timeout exceeded while waiting for running callbacks")
+
+// FileWatch continuously watches the importDir of the default pipeline, see Pipeline.FileWatch.
 func FileWatch(callback func(string) bool) {
-	if _, err := os.Stat(config.importDir); os.IsNotExist(err) {
-		Logging(fmt.Sprintf("Cannot find importDir: %v", err), CRITICAL)
-		return
+	defaultPipeline().FileWatch(callback)
+}
+
+// FileWatchContext watches the importDir of the default pipeline until ctx is cancelled, see
+// Pipeline.FileWatchContext.
+func FileWatchContext(ctx context.Context, callback func(string) bool) error {
+	return defaultPipeline().FileWatchContext(ctx, callback)
+}
+
+// FileWatchEvents watches the importDir of the default pipeline until ctx is cancelled, see
+// Pipeline.FileWatchEvents.
+func FileWatchEvents(ctx context.Context, handler EventHandler) error {
+	return defaultPipeline().FileWatchEvents(ctx, handler)
+}
+
+// FileMove moves a file to the processedDir or errorDir of the default pipeline, see Pipeline.FileMove.
+func FileMove(path string, ok bool) error {
+	return defaultPipeline().FileMove(path, ok)
+}
+
+// RelPath returns the path of a file relative to the importDir of the default pipeline, see Pipeline.RelPath.
+func RelPath(path string) string {
+	return defaultPipeline().RelPath(path)
+}
+
+// FileWatch continuously watches the directory specified in config.importDir for new file creation events.
+// When a new file is detected, it waits until the file is completely written (see the 'stable*' config keys) before
+// executing the provided callback function with the file path as an argument. If the callback returns true, the file
+// is moved to the processed directory; otherwise, it is moved to the error directory. Files that are already present
+// in importDir when the watch starts are processed first, oldest modification time first. The number of concurrent
+// callbacks is limited by the 'maxWorkers' config key. Logging is performed for critical errors during the process.
+func (p *Pipeline) FileWatch(callback func(string) bool) {
+	err := p.FileWatchContext(context.Background(), callback)
+	if err != nil {
+		p.Logging(fmt.Sprintf("FileWatch stopped: %v", err), CRITICAL)
+	}
+}
+
+// FileWatchContext behaves like FileWatch, but stops watching as soon as ctx is cancelled. After cancellation no new
+// files are accepted, and the callbacks and FileMove calls that are already running are given config.shutdownTimeout
+// to finish (a zero timeout waits indefinitely). Callbacks that exceeded config.callbackTimeout are not waited for.
+// Files that were detected but whose callback did not start yet are left in importDir. Returns nil after a graceful
+// shutdown, ErrShutdownTimeout when running callbacks did not finish in time, or the error that stopped the watch.
+func (p *Pipeline) FileWatchContext(ctx context.Context, callback func(string) bool) error {
+	return p.fileWatch(ctx, singleRoute(BoolHandler(callback)))
+}
+
+// FileWatchEvents behaves like FileWatchContext, but hands each file to an EventHandler. The handler receives a
+// FileEvent describing the file, and returns an error explaining why the file could not be processed. This error is
+// logged, written to a '.error.txt' sidecar file next to the file in errorDir, and passed to the 'fileHook' config
+// key.
+func (p *Pipeline) FileWatchEvents(ctx context.Context, handler EventHandler) error {
+	if handler == nil {
+		return errors.New("no handler given")
 	}
+	return p.fileWatch(ctx, singleRoute(handler))
+}
 
-	watcher, err := fsnotify.NewWatcher()
+// fileWatch implements FileWatchContext, FileWatchEvents and FileWatchRouter.
+func (p *Pipeline) fileWatch(ctx context.Context, router *Router) error {
+	if _, err := os.Stat(p.config.importDir); err != nil {
+		return fmt.Errorf("cannot find importDir: %w", err)
+	}
+
+	backend, err := p.newWatchBackend()
 	if err != nil {
-		Logging(fmt.Sprintf("Error while starting watching importDir: %v", err), CRITICAL)
-		return
+		return fmt.Errorf("cannot start watching importDir: %w", err)
 	}
-	defer func(watcher *fsnotify.Watcher) {
-		err = watcher.Close()
+	defer func(backend watchBackend) {
+		err := backend.Close()
 		if err != nil {
-			Logging(fmt.Sprintf("Error while closing watch on importDir: %v", err), CRITICAL)
+			p.Logging(fmt.Sprintf("Error while closing watch on importDir: %v", err), ERROR)
 		}
-	}(watcher)
+	}(backend)
 
-	err = watcher.Add(config.importDir)
+	w := &watchRun{p: p, ctx: ctx, router: router, backend: backend, pool: newWorkerPool(p.config)}
+	err = w.watchTree(p.config.importDir)
 	if err != nil {
-		return
+		return fmt.Errorf("cannot start watching importDir: %w", err)
+	}
+
+	// Files created from here on are reported by the watcher, the ones created before are picked up by the sweep
+	w.pool.running.run(func() {
+		w.sweep(p.config.importDir)
+	})
+	if p.config.archiveCompressAfter > 0 || p.config.archiveRetention > 0 {
+		w.pool.running.run(func() {
+			p.maintainArchive(ctx)
+		})
 	}
 
 	for {
 		select {
-		case event, ok := <-watcher.Events:
+		case <-ctx.Done():
+			p.Logging("Stopping watch on importDir, waiting for running callbacks to finish", INFO)
+			return w.stop()
+		case filePath, ok := <-backend.Events():
 			if !ok {
-				Logging("An unknown fatal error occurred while watching importDir", CRITICAL)
-				return
+				return errors.Join(errors.New("watch on importDir was closed unexpectedly"), w.stop())
 			}
-			if event.Op&fsnotify.Create == fsnotify.Create {
-				filePath := event.Name
-				go func(filePath string) {
-					time.Sleep(1 * time.Second) // Wait 1 second before triggering to ensure completion of file write
-					fileOk := callback(filePath)
-					FileMove(filePath, fileOk)
-				}(filePath)
+			if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+				w.addDir(filePath)
+				continue
 			}
-		case err, ok := <-watcher.Errors:
+			w.submit(filePath, p.config.queueFullPolicy != QueueFullSkip)
+		case <-w.pool.freed:
+			w.submitSkipped()
+		case err, ok := <-backend.Errors():
 			if !ok {
-				Logging(fmt.Sprintf("Fatal error while watching importDir: %v", err), CRITICAL)
-				return
+				return errors.Join(errors.New("watch on importDir was closed unexpectedly"), w.stop())
 			}
-			Logging(fmt.Sprintf("Non-fatal error while watching importDir: %v", err), ERROR)
+			p.Logging(fmt.Sprintf("Non-fatal error while watching importDir: %v", err), ERROR)
 		}
 	}
 }
 
+// watchRun holds the state of a single watch.
+type watchRun struct {
+	p       *Pipeline
+	ctx     context.Context
+	router  *Router
+	backend watchBackend
+	claimed claimSet
+	pool    *workerPool
+
+	mu      sync.Mutex
+	skipped []string // Files left in importDir because the queue was full, queued again when a slot is freed
+}
+
+// stop waits up to config.shutdownTimeout for the running callbacks to finish. Callbacks that already timed out are
+// not waited for, as a func(string) bool callback cannot be cancelled and may never return.
+func (w *watchRun) stop() error {
+	err := w.pool.wait(w.p.config.shutdownTimeout)
+	if n := w.pool.abandoned.active(); n > 0 {
+		w.p.Logging(fmt.Sprintf("Not waiting for %d callback(s) that are still running after their timeout", n), WARNING)
+	}
+	return err
+}
+
+// watchTree starts watching root and, when config.recursive is set, all of its subdirectories.
+func (w *watchRun) watchTree(root string) error {
+	if !w.p.config.recursive {
+		return w.backend.Add(root)
+	}
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return w.backend.Add(path)
+		}
+		return nil
+	})
+}
+
+// addDir starts watching a directory created inside importDir when config.recursive is set, and processes the files
+// that were written to it before the watch was added.
+func (w *watchRun) addDir(dir string) {
+	if !w.p.config.recursive {
+		return
+	}
+
+	err := w.watchTree(dir)
+	if err != nil {
+		w.p.Logging(fmt.Sprintf("Cannot start watching subdirectory '%s': %v", dir, err), ERROR)
+		return
+	}
+	w.p.Logging(fmt.Sprintf("Started watching subdirectory '%s'", dir), DEBUG)
+	w.pool.running.run(func() {
+		w.sweep(dir)
+	})
+}
+
+// submit queues filePath for processing by the worker pool. Files rejected by the include and exclude filters are
+// left in importDir. A file arriving while the queue is full is also left in importDir if block is false, and queued
+// again by submitSkipped once a slot is freed. A file that is already queued or being processed, such as a file the
+// polling backend reports again while it is still being written, is not queued twice. Returns false if the file was
+// skipped because the queue was full.
+func (w *watchRun) submit(filePath string, block bool) bool {
+	if !w.p.acceptFile(filePath) {
+		w.p.Logging(fmt.Sprintf("Ignoring '%s', it is excluded by the filename filters", filePath), DEBUG)
+		return true
+	}
+	if !w.claimed.claim(filePath) {
+		w.p.Logging(fmt.Sprintf("Ignoring '%s', it is already queued or being processed", filePath), DEBUG)
+		return true
+	}
+
+	event := FileEvent{
+		Path:      filePath,
+		RelPath:   w.p.RelPath(filePath),
+		Detected:  time.Now(),
+		Attempt:   1,
+		Pipeline:  w.p.name,
+		ctx:       w.ctx,
+		abandoned: &w.pool.abandoned,
+		idle:      w.pool.idle,
+	}
+	if w.p.config.instrumentFunc != nil {
+		event.Instrument = w.p.config.instrumentFunc(filePath)
+	}
+
+	queued := w.pool.submit(w.ctx, event.Instrument, func() {
+		defer w.claimed.release(filePath)
+		w.handle(event)
+	}, block)
+	if queued {
+		return true
+	}
+	w.claimed.release(filePath)
+	if w.ctx.Err() != nil {
+		return true
+	}
+	w.p.Logging(fmt.Sprintf("Worker queue is full, leaving '%s' in importDir until a queued file is done", filePath),
+		WARNING)
+	w.mu.Lock()
+	w.skipped = append(w.skipped, filePath)
+	w.mu.Unlock()
+	return false
+}
+
+// submitSkipped queues the files that were skipped because the queue was full, in the order they arrived, until the
+// queue is full again. Files that were removed from importDir in the meantime are dropped.
+func (w *watchRun) submitSkipped() {
+	w.mu.Lock()
+	skipped := w.skipped
+	w.skipped = nil
+	w.mu.Unlock()
+
+	for i, filePath := range skipped {
+		if _, err := os.Stat(filePath); err != nil {
+			continue
+		}
+		if !w.submit(filePath, false) {
+			// submit added filePath to the list again, the files after it keep waiting behind it
+			w.mu.Lock()
+			w.skipped = append(w.skipped, skipped[i+1:]...)
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+// handle processes the file of event with the handler of the matching route, unless it is a directory.
+func (w *watchRun) handle(event FileEvent) {
+	filePath := event.Path
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		return
+	}
+
+	handler, ok := w.router.match(filePath)
+	if !ok {
+		switch w.router.unmatched {
+		case UnmatchedIgnore:
+			w.p.Logging(fmt.Sprintf("Ignoring '%s', it matches none of the routes", filePath), DEBUG)
+			return
+		case UnmatchedLeave:
+			w.p.Logging(fmt.Sprintf("File '%s' matches none of the routes, leaving it in importDir", filePath), WARNING)
+			return
+		default:
+			handler = func(FileEvent) error {
+				return errors.New("file matches none of the routes")
+			}
+		}
+	}
+
+	w.p.processFile(event, handler)
+}
+
+// sweep queues the files that were already present in dir (and its subdirectories when config.recursive is set) in
+// order of modification time, without waiting for them to be processed. They are processed concurrently up to
+// config.maxWorkers; with config.serializeInstrument the files of an instrument are processed one by one in that
+// order. Files that are claimed by the watcher in the meantime are skipped.
+func (w *watchRun) sweep(dir string) {
+	type existingFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []existingFile
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !w.p.config.recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil // Removed since the directory was read
+		}
+		files = append(files, existingFile{path, info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		w.p.Logging(fmt.Sprintf("Cannot read '%s' for existing files: %v", dir, err), ERROR)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	w.p.Logging(fmt.Sprintf("Found %d existing file(s) in '%s', processing them first", len(files), dir), INFO)
+
+	for _, file := range files {
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.submit(file.path, true)
+	}
+}
+
+// processFile waits for the file to be written completely, executes the callback and moves the file accordingly.
+// Retryable failures are retried with an exponential backoff, up to config.maxAttempts attempts.
+// If ctx is cancelled before the callback is started, the file is left untouched in importDir. Files that no longer
+// exist, because they were already processed, are skipped, and files that never stabilise are left in importDir.
+func (p *Pipeline) processFile(event FileEvent, handler EventHandler) {
+	ctx, filePath := event.Context(), event.Path
+	err := p.waitStable(ctx, filePath)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		p.Logging(fmt.Sprintf("Skipping '%s', it is no longer present in importDir", filePath), DEBUG)
+		return
+	case ctx.Err() != nil:
+		p.Logging(fmt.Sprintf("Watch stopped before '%s' was processed, leaving it in importDir", filePath), INFO)
+		return
+	case errors.Is(err, errNotStable):
+		p.Logging(fmt.Sprintf("File '%s' is still being written: %v, leaving it in importDir", filePath, err), ERROR)
+		return
+	default:
+		p.Logging(fmt.Sprintf("Cannot check whether '%s' is completely written: %v", filePath, err), ERROR)
+		return
+	}
+
+	if info, err := os.Stat(filePath); err == nil {
+		event.Size = info.Size()
+	}
+
+	hash, duplicate := p.checkDuplicateFile(filePath)
+	if duplicate {
+		if _, moveErr := p.moveFile(filePath, true); moveErr != nil {
+			p.Logging(fmt.Sprintf("Error while moving file: %v", moveErr), ERROR)
+		}
+		if p.config.fileHook != nil {
+			p.config.fileHook(event, ErrDuplicate)
+		}
+		return
+	}
+
+	stopped, err := p.runWithRetry(&event, handler)
+	if stopped {
+		p.Logging(fmt.Sprintf("Watch stopped before '%s' was retried, leaving it in importDir", filePath), INFO)
+		return
+	}
+	if err == nil {
+		p.recordFile(filePath, hash)
+	}
+
+	destPath, moveErr := p.moveFile(filePath, err == nil)
+	if moveErr != nil {
+		p.Logging(fmt.Sprintf("Error while moving file, leaving it in importDir: %v", moveErr), ERROR)
+		// Report the file as failed, it was not archived
+		err = errors.Join(err, fmt.Errorf("cannot move file: %w", moveErr))
+	} else if err != nil && !errors.Is(err, ErrCallbackFailed) {
+		p.writeErrorSidecar(destPath, event, err)
+		var timeout *callbackTimeoutError
+		if errors.As(err, &timeout) {
+			event.abandoned.run(func() {
+				p.settleTimedOut(event, destPath, hash, timeout.late)
+			})
+			p.Logging(fmt.Sprintf("Callback for '%s' keeps running after its timeout, %d timed out callback(s) running",
+				filePath, event.abandoned.active()), WARNING)
+		}
+	}
+
+	if p.config.fileHook != nil {
+		p.config.fileHook(event, err)
+	}
+}
+
+// settleTimedOut waits for the handler of a file that was moved to errorDir after a timeout. If the handler succeeds
+// after all, its output was delivered, so the file is moved on to processedDir to prevent it from being delivered
+// again by Reprocess.
+func (p *Pipeline) settleTimedOut(event FileEvent, errorPath string, hash string, late <-chan error) {
+	if err := <-late; err != nil {
+		p.Logging(fmt.Sprintf("Callback for '%s' returned after its timeout: %v", event.Path, err), INFO)
+		return
+	}
+	p.Logging(fmt.Sprintf("Callback for '%s' succeeded after its timeout, moving it from errorDir to processedDir",
+		event.Path), WARNING)
+
+	subDir := "."
+	if rel, inImport := p.importRel(event.Path); inImport {
+		subDir = filepath.Dir(rel)
+	}
+	// The file is staged under its original name, as archiveFile prefixes the name with a new timestamp
+	stagedPath := filepath.Join(p.config.errorDir, reprocessDir, subDir, filepath.Base(event.Path))
+	err := os.MkdirAll(filepath.Dir(stagedPath), os.ModePerm)
+	if err == nil {
+		err = rename(errorPath, stagedPath)
+	}
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot move '%s' out of errorDir: %v", errorPath, err), ERROR)
+		return
+	}
+	p.recordFile(stagedPath, hash)
+	if _, err = p.archiveFile(stagedPath, subDir, true); err != nil {
+		p.Logging(fmt.Sprintf("Cannot move '%s' to processedDir: %v", stagedPath, err), ERROR)
+		return
+	}
+	if err = os.Remove(errorPath + errorSidecarSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		p.Logging(fmt.Sprintf("Cannot remove error reason of '%s': %v", errorPath, err), WARNING)
+	}
+}
+
+// runWithRetry runs handler for event, retrying retryable failures with an exponential backoff up to
+// config.maxAttempts attempts. The Attempt of event is updated accordingly. During the backoff the worker is released
+// for other files; with config.serializeInstrument the next files of the same instrument still wait, to keep them in
+// order. Returns true if the context of event was cancelled while waiting for a retry, and the error of the last
+// attempt.
+func (p *Pipeline) runWithRetry(event *FileEvent, handler EventHandler) (bool, error) {
+	for {
+		err := p.runHandler(*event, handler)
+		if err == nil || !IsRetryable(err) || event.Attempt >= max(p.config.maxAttempts, 1) {
+			if err != nil && !errors.Is(err, ErrCallbackFailed) {
+				p.Logging(fmt.Sprintf("Processing '%s' failed: %v", event.Path, err), ERROR)
+			}
+			return false, err
+		}
+
+		delay := p.retryDelay(event.Attempt)
+		p.Logging(fmt.Sprintf("Processing '%s' failed (attempt %d of %d), retrying in %s: %v", event.Path,
+			event.Attempt, p.config.maxAttempts, delay, err), WARNING)
+		cancelled := false
+		backoff := func() {
+			select {
+			case <-time.After(delay):
+			case <-event.Context().Done():
+				cancelled = true
+			}
+		}
+		if event.idle != nil {
+			event.idle(backoff)
+		} else {
+			backoff()
+		}
+		if cancelled {
+			return true, err
+		}
+		event.Attempt++
+	}
+}
+
+// claimSet holds the paths that are currently queued or being processed, so a file is never handed to two callbacks
+// at once.
+type claimSet struct {
+	mu    sync.Mutex
+	paths map[string]struct{}
+}
+
+// claim marks path as queued. Returns false if it was already claimed.
+func (c *claimSet) claim(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paths == nil {
+		c.paths = make(map[string]struct{})
+	}
+	if _, exists := c.paths[path]; exists {
+		return false
+	}
+	c.paths[path] = struct{}{}
+	return true
+}
+
+// release removes the claim on path.
+func (c *claimSet) release(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.paths, path)
+}
+
+// RelPath returns the path of a file in importDir relative to importDir, e.g. 'run_0412/plate1.csv' for a file in a
+// subdirectory of importDir when watching recursively. Paths outside importDir are returned unchanged.
+func (p *Pipeline) RelPath(path string) string {
+	rel, ok := p.importRel(path)
+	if !ok {
+		return path
+	}
+	return rel
+}
+
+// importRel returns the path relative to importDir, or false if path is not inside importDir.
+func (p *Pipeline) importRel(path string) (string, bool) {
+	rel, err := filepath.Rel(p.config.importDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
 // FileMove moves a file from the given path to a processed or error directory based on the status flag (ok).
-func FileMove(path string, ok bool) {
-	timestamp := strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
+// Files from a subdirectory of importDir are moved into the same subdirectory of the destination, unless
+// config.flattenSubdirs is set. When the destination is on another filesystem, the file is copied and verified
+// before the original is removed. Returns an error if the file could not be moved, in which case it is left in place.
+func (p *Pipeline) FileMove(path string, ok bool) error {
+	_, err := p.moveFile(path, ok)
+	if err != nil {
+		p.Logging(fmt.Sprintf("Error while moving file: %v", err), ERROR)
+	}
+	return err
+}
+
+// moveFile implements FileMove, returning the destination of the file.
+func (p *Pipeline) moveFile(path string, ok bool) (string, error) {
+	subDir := "."
+	if rel, inImport := p.importRel(path); inImport {
+		subDir = filepath.Dir(rel)
+	}
+	return p.archiveFile(path, subDir, ok)
+}
+
+// archiveFile moves a file into subDir of the processed or error directory, prefixing its name with a timestamp.
+// Within processedDir, subDir is placed in the partition given by config.archiveLayout. Returns the destination of
+// the file.
+func (p *Pipeline) archiveFile(path string, subDir string, ok bool) (string, error) {
+	now := time.Now()
+	timestamp := strings.ReplaceAll(now.Format(archiveTimestampLayout), ".", "")
 	FileName := fmt.Sprintf("%s_%s", timestamp, filepath.Base(path))
-	var destPath string
+	var destDir string
 
 	if ok {
-		destPath = filepath.Join(config.processedDir, FileName)
+		destDir = filepath.Join(p.config.processedDir, p.archivePartition(path, now))
 	} else {
-		destPath = filepath.Join(config.errorDir, FileName)
+		destDir = p.config.errorDir
+	}
+
+	if !p.config.flattenSubdirs && subDir != "." {
+		destDir = filepath.Join(destDir, subDir)
+	}
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		return "", err
 	}
+	destPath := filepath.Join(destDir, FileName)
 
-	err := os.Rename(path, destPath)
+	err := moveAcross(path, destPath)
 	if err != nil {
-		Logging(fmt.Sprintf("Error while moving file: %v", err), ERROR)
+		return "", err
 	}
+	return destPath, nil
 }
@@ -1,8 +1,12 @@
 package FlowG
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -115,7 +119,10 @@ func TestFileMove(t *testing.T) {
 				}
 			}
 
-			FileMove(originalPath, c.okProcessing)
+			err = FileMove(originalPath, c.okProcessing)
+			if (err == nil) != c.validPath {
+				t.Errorf("FileMove returned error %v for valid path %v", err, c.validPath)
+			}
 
 			processedFileList, err := filepath.Glob(filepath.Join(config.processedDir, "*_"+filepath.Base(originalPath)))
 			if err != nil {
@@ -295,3 +302,348 @@ func TestFileWatch(t *testing.T) {
 		})
 	}
 }
+
+func TestFileWatchContext(t *testing.T) {
+	cases := []struct {
+		name            string
+		validFolder     bool
+		callbackTime    time.Duration
+		shutdownTimeout time.Duration
+		wantErr         error
+		wantProcessed   int
+	}{
+		{"Invalid folder", false, 0, 0, os.ErrNotExist, 0},
+		{"Graceful shutdown waits for callback", true, 500 * time.Millisecond, 0, nil, 1},
+		{"Shutdown timeout exceeded", true, 2 * time.Second, 100 * time.Millisecond, ErrShutdownTimeout, 0},
+	}
+
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config.shutdownTimeout = c.shutdownTimeout
+			if c.validFolder {
+				err := createTestFolders()
+				if err != nil {
+					t.Fatalf("Error creating test folders: %v", err)
+				}
+			}
+			defer func() {
+				err := destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+
+			callbackStarted := make(chan struct{}, 1)
+			callback := func(path string) bool {
+				callbackStarted <- struct{}{}
+				time.Sleep(c.callbackTime)
+				return true
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchContext(ctx, callback)
+			}()
+
+			if c.validFolder {
+				// Allow time for FileWatchContext to initialize
+				time.Sleep(100 * time.Millisecond)
+				err := os.WriteFile(filepath.Join(config.importDir, "testFile.txt"), []byte("data"), 0644)
+				if err != nil {
+					t.Fatalf("Error creating test file: %v", err)
+				}
+
+				select {
+				case <-callbackStarted:
+				case <-time.After(3 * time.Second):
+					t.Fatalf("FileWatchContext() got timeout, expected file detection")
+				}
+				cancel()
+			}
+
+			select {
+			case err := <-result:
+				if !errors.Is(err, c.wantErr) {
+					t.Errorf("FileWatchContext() returned error %v, wanted %v", err, c.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("FileWatchContext() did not return after cancellation")
+			}
+
+			processedFileList, _ := filepath.Glob(filepath.Join(config.processedDir, "*_testFile.txt"))
+			if len(processedFileList) != c.wantProcessed {
+				t.Errorf("Expected %d processed file(s) on return, got %d", c.wantProcessed, len(processedFileList))
+			}
+
+			// Let a callback that outlived the shutdown timeout finish before the folders are removed
+			time.Sleep(c.callbackTime)
+		})
+	}
+}
+
+func TestFileWatchExistingFiles(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		serializeInstrument: true,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	// Create the files out of order, the sweep should follow the modification time
+	existing := []struct {
+		name string
+		age  time.Duration
+	}{
+		{"newest.txt", time.Minute},
+		{"oldest.txt", time.Hour},
+		{"middle.txt", 30 * time.Minute},
+	}
+	for _, file := range existing {
+		path := filepath.Join(config.importDir, file.name)
+		err = os.WriteFile(path, []byte("data"), 0644)
+		if err != nil {
+			t.Fatalf("Error creating test file: %v", err)
+		}
+		err = os.Chtimes(path, time.Now().Add(-file.age), time.Now().Add(-file.age))
+		if err != nil {
+			t.Fatalf("Error setting test file modification time: %v", err)
+		}
+	}
+
+	processed := make(chan string, 10)
+	callback := func(path string) bool {
+		processed <- filepath.Base(path)
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- FileWatchContext(ctx, callback)
+	}()
+
+	// A file arriving during the sweep is processed by the watcher instead
+	time.Sleep(100 * time.Millisecond)
+	err = os.WriteFile(filepath.Join(config.importDir, "live.txt"), []byte("data"), 0644)
+	if err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+
+	var order []string
+	timeout := time.After(5 * time.Second)
+	for len(order) < 4 {
+		select {
+		case name := <-processed:
+			order = append(order, name)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for files to be processed, got %v", order)
+		}
+	}
+
+	// Give the watcher time to (wrongly) process a file a second time
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchContext() returned error %v", err)
+	}
+	close(processed)
+	for name := range processed {
+		order = append(order, name)
+	}
+
+	var sweepOrder []string
+	for _, name := range order {
+		if name != "live.txt" {
+			sweepOrder = append(sweepOrder, name)
+		}
+	}
+	wantOrder := []string{"oldest.txt", "middle.txt", "newest.txt"}
+	if len(order) != 4 || !reflect.DeepEqual(sweepOrder, wantOrder) {
+		t.Errorf("Expected existing files in order %v and the live file once, got %v", wantOrder, order)
+	}
+
+	remaining, _ := os.ReadDir(config.importDir)
+	if len(remaining) != 0 {
+		t.Errorf("Expected importDir to be empty, got %d file(s)", len(remaining))
+	}
+}
+
+func TestFileWatchExistingFilesConcurrently(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		stableQuietPeriod: 500 * time.Millisecond,
+		maxWorkers:        3,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	const files = 6
+	for i := 0; i < files; i++ {
+		err = os.WriteFile(filepath.Join(config.importDir, fmt.Sprintf("file%d.txt", i)), []byte("data"), 0644)
+		if err != nil {
+			t.Fatalf("Error creating test file: %v", err)
+		}
+	}
+
+	processed := make(chan string, files)
+	callback := func(path string) bool {
+		processed <- filepath.Base(path)
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		result <- FileWatchContext(ctx, callback)
+	}()
+
+	// Processed one by one, every file would wait the quiet period in turn
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < files; i++ {
+		select {
+		case <-processed:
+		case <-timeout:
+			t.Fatalf("Timeout waiting for files to be processed, got %d", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > files*config.stableQuietPeriod/2 {
+		t.Errorf("Expected the existing files to be processed by %d workers at once, took %s", config.maxWorkers, elapsed)
+	}
+
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchContext() returned error %v", err)
+	}
+}
+
+func TestFileWatchRecursive(t *testing.T) {
+	cases := []struct {
+		name     string
+		backend  string
+		flatten  bool
+		wantDest string
+	}{
+		{"fsnotify backend, mirrored", BackendFsnotify, false, filepath.Join("run_01", "nested")},
+		{"Polling backend, mirrored", BackendPoll, false, filepath.Join("run_01", "nested")},
+		{"fsnotify backend, flattened", BackendFsnotify, true, "."},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       WARNING,
+
+				stableQuietPeriod: 100 * time.Millisecond,
+				watcherBackend:    c.backend,
+				pollInterval:      50 * time.Millisecond,
+				recursive:         true,
+				flattenSubdirs:    c.flatten,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			relPaths := make(chan string, 2)
+			callback := func(path string) bool {
+				relPaths <- RelPath(path)
+				return true
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchContext(ctx, callback)
+			}()
+
+			// Allow time for FileWatchContext to initialize
+			time.Sleep(100 * time.Millisecond)
+			subDir := filepath.Join(config.importDir, "run_01", "nested")
+			err = os.MkdirAll(subDir, os.ModePerm)
+			if err != nil {
+				t.Fatalf("Error creating test subdirectory: %v", err)
+			}
+			err = os.WriteFile(filepath.Join(subDir, "testFile.txt"), []byte("data"), 0644)
+			if err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			select {
+			case rel := <-relPaths:
+				if want := filepath.Join("run_01", "nested", "testFile.txt"); rel != want {
+					t.Errorf("Expected relative path '%s', got '%s'", want, rel)
+				}
+			case <-time.After(3 * time.Second):
+				t.Errorf("FileWatchContext() got timeout, expected file detection in subdirectory")
+			}
+
+			cancel()
+			if err = <-result; err != nil {
+				t.Errorf("FileWatchContext() returned error %v", err)
+			}
+
+			processedFileList, _ := filepath.Glob(filepath.Join(config.processedDir, c.wantDest, "*_testFile.txt"))
+			if len(processedFileList) != 1 {
+				t.Errorf("Expected the file to be archived in '%s'", filepath.Join(config.processedDir, c.wantDest))
+			}
+		})
+	}
+}
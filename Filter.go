@@ -0,0 +1,89 @@
+package FlowG
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// regexpPrefix marks a filename pattern as a regular expression instead of a glob.
+const regexpPrefix = "re:"
+
+// filePattern matches file names against a glob (see filepath.Match) or, when prefixed with 're:', a regular
+// expression.
+type filePattern struct {
+	source string
+	glob   string
+	re     *regexp.Regexp
+}
+
+// compilePattern validates and compiles a single filename pattern.
+func compilePattern(pattern string) (filePattern, error) {
+	if expr, isRegexp := strings.CutPrefix(pattern, regexpPrefix); isRegexp {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return filePattern{}, fmt.Errorf("invalid regular expression '%s': %w", expr, err)
+		}
+		return filePattern{source: pattern, re: re}, nil
+	}
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return filePattern{}, fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+	}
+	return filePattern{source: pattern, glob: pattern}, nil
+}
+
+// compilePatterns validates and compiles a list of filename patterns.
+func compilePatterns(patterns []string) ([]filePattern, error) {
+	compiled := make([]filePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// patternSources returns the patterns as they were configured.
+func patternSources(patterns []filePattern) []string {
+	if patterns == nil {
+		return nil
+	}
+	sources := make([]string, len(patterns))
+	for i, p := range patterns {
+		sources[i] = p.source
+	}
+	return sources
+}
+
+// match reports whether the base name of path matches the pattern.
+func (p filePattern) match(path string) bool {
+	name := filepath.Base(path)
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := filepath.Match(p.glob, name)
+	return ok
+}
+
+// matchAny reports whether path matches at least one of the patterns.
+func matchAny(patterns []filePattern, path string) bool {
+	for _, p := range patterns {
+		if p.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptFile reports whether path passes the configured include and exclude filters. Without include patterns all
+// files are included.
+func (p *Pipeline) acceptFile(path string) bool {
+	if len(p.config.includePatterns) > 0 && !matchAny(p.config.includePatterns, path) {
+		return false
+	}
+	return !matchAny(p.config.excludePatterns, path)
+}
@@ -0,0 +1,41 @@
+package FlowG
+
+import "testing"
+
+func TestAcceptFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		path     string
+		expected bool
+	}{
+		{"No filters", nil, nil, "import/result.csv", true},
+		{"Included by glob", []string{"*.csv"}, nil, "import/result.csv", true},
+		{"Not included by glob", []string{"*.csv"}, nil, "import/result.xml", false},
+		{"Included by regexp", []string{`re:^run_\d+\.xml$`}, nil, "import/run_12.xml", true},
+		{"Not included by regexp", []string{`re:^run_\d+\.xml$`}, nil, "import/run_ab.xml", false},
+		{"Excluded by glob", nil, []string{"*.tmp", "*.lock"}, "import/result.lock", false},
+		{"Excluded case-insensitive by regexp", nil, []string{`re:(?i)^thumbs\.db$`}, "import/Thumbs.db", false},
+		{"Included and excluded", []string{"*.csv"}, []string{"audit_*"}, "import/audit_1.csv", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{}
+			err := SetConfig("includePatterns", c.include)
+			if err != nil {
+				t.Fatalf("Error setting includePatterns: %v", err)
+			}
+			err = SetConfig("excludePatterns", c.exclude)
+			if err != nil {
+				t.Fatalf("Error setting excludePatterns: %v", err)
+			}
+
+			actual := defaultPipeline().acceptFile(c.path)
+			if actual != c.expected {
+				t.Errorf("acceptFile(%q): expected %v, got %v", c.path, c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package FlowG
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode determines how a result is rounded to the digits of its NumberFormat.
+type RoundingMode uint8
+
+// Available rounding modes. All modes except RoundNearest round the shortest decimal representation of the value,
+// so 2.675 is treated as exactly 2.675 rather than as the binary value just below it.
+const (
+	RoundNearest  RoundingMode = iota // Round the exact binary value to the nearest digit, like strconv (default)
+	RoundHalfUp                       // Round to the nearest digit, ties away from zero
+	RoundHalfEven                     // Round to the nearest digit, ties to the even digit
+	RoundDown                         // Truncate towards zero
+	RoundUp                           // Round away from zero
+)
+
+// maxDigits is the maximum number of decimals or significant digits of a NumberFormat.
+const maxDigits = 17
+
+// defaultFormatKey is the key of the 'numberFormats' config key that replaces the default format.
+const defaultFormatKey = "*"
+
+// NumberFormat describes how the numeric results of a test are written to the FlowG output.
+type NumberFormat struct {
+	Decimals          int          // Number of digits after the decimal point
+	SignificantDigits int          // Number of significant digits, replaces Decimals when set
+	Rounding          RoundingMode // How the value is rounded to the digits
+	Scientific        bool         // Use scientific notation, such as 1.28e+03
+	TrimZeros         bool         // Remove trailing zeros after the decimal point, and the point itself if possible
+}
+
+// DefaultNumberFormat is used for tests without a format in the 'numberFormats' config key.
+var DefaultNumberFormat = NumberFormat{Decimals: 2}
+
+// validate checks that the settings of f are within range.
+func (f NumberFormat) validate() error {
+	if f.Decimals < 0 || f.Decimals > maxDigits {
+		return fmt.Errorf("decimals must be between 0 and %d", maxDigits)
+	}
+	if f.SignificantDigits < 0 || f.SignificantDigits > maxDigits {
+		return fmt.Errorf("significant digits must be between 0 and %d", maxDigits)
+	}
+	if f.Rounding > RoundUp {
+		return fmt.Errorf("unknown rounding mode %d", f.Rounding)
+	}
+	return nil
+}
+
+// Format formats value according to f. NaN and infinite values are written as strconv does.
+func (f NumberFormat) Format(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	var formatted string
+	if f.Rounding == RoundNearest && !f.Scientific && f.SignificantDigits == 0 {
+		formatted = strconv.FormatFloat(value, 'f', f.Decimals, 64)
+		// A negative value that rounds to zero is written as zero, like the other rounding modes do
+		if strings.Trim(formatted, "-0.") == "" {
+			formatted = strings.TrimPrefix(formatted, "-")
+		}
+	} else {
+		d := f.round(value)
+		switch {
+		case f.Scientific:
+			formatted = d.scientific()
+		case f.SignificantDigits > 0:
+			formatted = d.fixed(max(f.SignificantDigits-d.exp, 0))
+		default:
+			formatted = d.fixed(f.Decimals)
+		}
+	}
+
+	if f.TrimZeros {
+		formatted = trimZeros(formatted)
+	}
+	return formatted
+}
+
+// round rounds value to the digits of f.
+func (f NumberFormat) round(value float64) decimal {
+	var d decimal
+	if f.Rounding == RoundNearest {
+		// Scientific and significant formats keep a fixed number of digits, so strconv can round them directly
+		digits := f.SignificantDigits
+		if digits == 0 {
+			digits = f.Decimals + 1
+		}
+		d = parseDecimal(strconv.FormatFloat(value, 'e', digits-1, 64))
+		d.precision = digits - 1
+		return d
+	}
+
+	d = parseDecimal(strconv.FormatFloat(value, 'e', -1, 64))
+	switch {
+	case f.SignificantDigits > 0:
+		d.round(f.SignificantDigits, f.Rounding)
+		d.precision = f.SignificantDigits - 1
+	case f.Scientific:
+		d.round(f.Decimals+1, f.Rounding)
+		d.precision = f.Decimals
+	default:
+		d.round(d.exp+f.Decimals, f.Rounding)
+	}
+	return d
+}
+
+// decimal is a decimal number 0.digits * 10^exp, as produced by strconv.
+type decimal struct {
+	neg       bool
+	digits    []byte // Without leading zeros, empty for zero
+	exp       int
+	precision int // Digits after the point of the mantissa in scientific notation
+}
+
+// parseDecimal parses a number formatted by strconv.FormatFloat with format 'e'.
+func parseDecimal(s string) decimal {
+	var d decimal
+	if s[0] == '-' {
+		d.neg = true
+		s = s[1:]
+	}
+	mantissa, exponent, _ := strings.Cut(s, "e")
+	exp, _ := strconv.Atoi(exponent)
+	d.digits = []byte(strings.Replace(mantissa, ".", "", 1))
+	d.exp = exp + 1
+	d.trim()
+	return d
+}
+
+// trim removes trailing zeros from the digits, and normalises zero.
+func (d *decimal) trim() {
+	d.digits = []byte(strings.TrimRight(string(d.digits), "0"))
+	if len(d.digits) == 0 {
+		d.exp = 0
+	}
+}
+
+// round rounds d to n digits according to mode. n may be zero or negative when the value is smaller than the last
+// digit that is kept.
+func (d *decimal) round(n int, mode RoundingMode) {
+	if n >= len(d.digits) {
+		return
+	}
+
+	// The first dropped digit and whether any digit after it is non-zero decide the rounding
+	first, rest := byte('0'), true
+	if n >= 0 {
+		first = d.digits[n]
+		rest = strings.TrimRight(string(d.digits[n+1:]), "0") != ""
+	} else {
+		d.exp -= n
+		n = 0
+	}
+	kept := d.digits[:n]
+
+	up := false
+	switch mode {
+	case RoundUp:
+		up = true
+	case RoundDown:
+		up = false
+	case RoundHalfUp:
+		up = first >= '5'
+	case RoundHalfEven:
+		last := byte('0')
+		if n > 0 {
+			last = kept[n-1]
+		}
+		up = first > '5' || (first == '5' && (rest || (last-'0')%2 == 1))
+	}
+
+	if up {
+		i := n - 1
+		for i >= 0 && kept[i] == '9' {
+			kept[i] = '0'
+			i--
+		}
+		if i >= 0 {
+			kept[i]++
+		} else {
+			kept = append([]byte{'1'}, kept...)
+			d.exp++
+		}
+	}
+	d.digits = kept
+	d.trim()
+}
+
+// fixed formats d with the given number of decimals.
+func (d decimal) fixed(decimals int) string {
+	var b strings.Builder
+	if d.neg && len(d.digits) > 0 {
+		b.WriteByte('-')
+	}
+
+	digit := func(i int) byte {
+		if i >= 0 && i < len(d.digits) {
+			return d.digits[i]
+		}
+		return '0'
+	}
+	if d.exp <= 0 {
+		b.WriteByte('0')
+	} else {
+		for i := 0; i < d.exp; i++ {
+			b.WriteByte(digit(i))
+		}
+	}
+	if decimals > 0 {
+		b.WriteByte('.')
+		for i := 0; i < decimals; i++ {
+			b.WriteByte(digit(d.exp + i))
+		}
+	}
+	return b.String()
+}
+
+// scientific formats d in scientific notation with d.precision digits after the point of the mantissa.
+func (d decimal) scientific() string {
+	exp := d.exp - 1
+	if len(d.digits) == 0 {
+		exp = 0
+	}
+	mantissa := decimal{neg: d.neg, digits: d.digits, exp: 1}
+	sign := '+'
+	if exp < 0 {
+		sign = '-'
+		exp = -exp
+	}
+	return fmt.Sprintf("%se%c%02d", mantissa.fixed(d.precision), sign, exp)
+}
+
+// trimZeros removes trailing zeros after the decimal point of a formatted number, and the point if nothing is left.
+func trimZeros(s string) string {
+	mantissa, exponent, scientific := strings.Cut(s, "e")
+	if strings.Contains(mantissa, ".") {
+		mantissa = strings.TrimRight(mantissa, "0")
+		mantissa = strings.TrimSuffix(mantissa, ".")
+	}
+	if scientific {
+		return mantissa + "e" + exponent
+	}
+	return mantissa
+}
+
+// numberFormat returns the format for the results of testName.
+func (p *Pipeline) numberFormat(testName string) NumberFormat {
+	if format, ok := p.config.numberFormats[testName]; ok {
+		return format
+	}
+	if format, ok := p.config.numberFormats[defaultFormatKey]; ok {
+		return format
+	}
+	return DefaultNumberFormat
+}
+
+// formatResult formats a result of testName for the FlowG output. Returns an empty string for a nil value.
+func (p *Pipeline) formatResult(testName string, value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return p.numberFormat(testName).Format(*value)
+}
@@ -0,0 +1,74 @@
+package FlowG
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNumberFormat(t *testing.T) {
+	cases := []struct {
+		name     string
+		format   NumberFormat
+		value    float64
+		expected string
+	}{
+		{"Default", DefaultNumberFormat, 27.456, "27.46"},
+		{"Default integer value", DefaultNumberFormat, 1280, "1280.00"},
+		{"No decimals", NumberFormat{}, 1280, "1280"},
+		{"Trim zeros", NumberFormat{Decimals: 2, TrimZeros: true}, 1280, "1280"},
+		{"Trim partial zeros", NumberFormat{Decimals: 3, TrimZeros: true}, 1.5, "1.5"},
+		{"Nearest uses binary value", NumberFormat{Decimals: 2}, 2.675, "2.67"},
+		{"Half up", NumberFormat{Decimals: 2, Rounding: RoundHalfUp}, 2.675, "2.68"},
+		{"Half up negative", NumberFormat{Decimals: 1, Rounding: RoundHalfUp}, -27.45, "-27.5"},
+		{"Half even down", NumberFormat{Decimals: 2, Rounding: RoundHalfEven}, 2.665, "2.66"},
+		{"Half even up", NumberFormat{Decimals: 2, Rounding: RoundHalfEven}, 2.675, "2.68"},
+		{"Down", NumberFormat{Decimals: 1, Rounding: RoundDown}, 27.459, "27.4"},
+		{"Nearest to zero", DefaultNumberFormat, -0.001, "0.00"},
+		{"Nearest to zero trimmed", NumberFormat{Decimals: 2, TrimZeros: true}, -0.001, "0"},
+		{"Negative zero", DefaultNumberFormat, math.Copysign(0, -1), "0.00"},
+		{"Down to zero", NumberFormat{Decimals: 2, Rounding: RoundDown}, -0.001, "0.00"},
+		{"Up", NumberFormat{Decimals: 1, Rounding: RoundUp}, 27.41, "27.5"},
+		{"Up below last digit", NumberFormat{Decimals: 2, Rounding: RoundUp}, 0.001, "0.01"},
+		{"Up with carry", NumberFormat{Decimals: 1, Rounding: RoundUp}, 9.96, "10.0"},
+		{"Significant digits", NumberFormat{SignificantDigits: 3}, 0.0012345, "0.00123"},
+		{"Significant digits large value", NumberFormat{SignificantDigits: 2}, 1280, "1300"},
+		{"Significant digits with carry", NumberFormat{SignificantDigits: 3, Rounding: RoundHalfUp}, 99.96, "100"},
+		{"Scientific", NumberFormat{Decimals: 2, Scientific: true}, 1280, "1.28e+03"},
+		{"Scientific small value", NumberFormat{Decimals: 2, Scientific: true, Rounding: RoundHalfUp}, 0.0001235, "1.24e-04"},
+		{"Scientific significant digits", NumberFormat{SignificantDigits: 4, Scientific: true, TrimZeros: true}, 1280, "1.28e+03"},
+		{"Scientific zero", NumberFormat{Decimals: 1, Scientific: true, Rounding: RoundDown}, 0, "0.0e+00"},
+		{"Not a number", DefaultNumberFormat, math.NaN(), "NaN"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.format.Format(c.value); actual != c.expected {
+				t.Errorf("%+v.Format(%v): expected %q, got %q", c.format, c.value, c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	cases := []struct {
+		name     string
+		formats  map[string]NumberFormat
+		testName string
+		value    *float64
+		expected string
+	}{
+		{"Nil value", nil, "CT", nil, ""},
+		{"Default format", nil, "CT", ptrFloat64(27.456), "27.46"},
+		{"Test format", map[string]NumberFormat{"Titer": {}}, "Titer", ptrFloat64(1280), "1280"},
+		{"Replaced default format", map[string]NumberFormat{"*": {Decimals: 1}}, "CT", ptrFloat64(27.456), "27.5"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{numberFormats: c.formats}
+			if actual := defaultPipeline().formatResult(c.testName, c.value); actual != c.expected {
+				t.Errorf("formatResult(%q): expected %q, got %q", c.testName, c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package FlowG
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Available behaviours for the 'duplicatePolicy' config key
+const (
+	DuplicateSkip  = "skip"  // Do not process duplicate files and leave duplicate results out of the output (default)
+	DuplicateWarn  = "warn"  // Log a warning, but process and deliver duplicates anyway
+	DuplicateForce = "force" // Process and deliver duplicates without checking the ledger
+)
+
+// Kinds of entries kept in the ledger, each stored in its own file in ledgerDir
+const (
+	ledgerFiles   = "files"
+	ledgerResults = "results"
+)
+
+// ErrDuplicate is passed to the 'fileHook' config key for files that were skipped because the same content was
+// processed before.
+var ErrDuplicate = errors.New("file was processed before")
+
+// ledger is a persistent record of the SHA-256 hashes of processed input files and delivered results. Every kind of
+// entry is stored in a '<kind>.ledger' file in the ledger directory, one hash per line followed by the time it was
+// recorded and a description. The files are loaded on first use.
+type ledger struct {
+	dir     string
+	mu      sync.Mutex
+	entries map[string]map[string]struct{}
+}
+
+// newLedger returns a ledger stored in dir.
+func newLedger(dir string) *ledger {
+	return &ledger{dir: dir}
+}
+
+// path returns the file in which the entries of kind are stored.
+func (l *ledger) path(kind string) string {
+	return filepath.Join(l.dir, kind+".ledger")
+}
+
+// load reads the entries of kind from disk if they were not loaded yet. The caller must hold l.mu.
+func (l *ledger) load(kind string) (map[string]struct{}, error) {
+	if entries, ok := l.entries[kind]; ok {
+		return entries, nil
+	}
+
+	entries := make(map[string]struct{})
+	file, err := os.Open(l.path(kind))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if hash, _, _ := strings.Cut(scanner.Text(), " "); hash != "" {
+				entries[hash] = struct{}{}
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.entries == nil {
+		l.entries = make(map[string]map[string]struct{})
+	}
+	l.entries[kind] = entries
+	return entries, nil
+}
+
+// contains reports whether hash was recorded as kind.
+func (l *ledger) contains(kind, hash string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.load(kind)
+	if err != nil {
+		return false, err
+	}
+	_, ok := entries[hash]
+	return ok, nil
+}
+
+// record appends the hashes that were not recorded as kind yet, with description, to the ledger.
+func (l *ledger) record(kind, description string, hashes ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.load(kind)
+	if err != nil {
+		return err
+	}
+
+	var lines strings.Builder
+	pending := make(map[string]struct{})
+	recorded := time.Now().Format(time.RFC3339)
+	for _, hash := range hashes {
+		if _, ok := entries[hash]; ok {
+			continue
+		}
+		if _, ok := pending[hash]; ok {
+			continue
+		}
+		pending[hash] = struct{}{}
+		lines.WriteString(fmt.Sprintf("%s %s %s\n", hash, recorded, description))
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(l.path(kind), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(lines.String()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	for hash := range pending {
+		entries[hash] = struct{}{}
+	}
+	return nil
+}
+
+// hashFile returns the SHA-256 hash of the contents of the file at path.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashRecord returns the SHA-256 hash of a FlowG record, so results are compared by the row delivered to GLIMS:
+// results that are written the same are duplicates, whatever precision or spelling they were passed with.
+func hashRecord(record []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(record, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// duplicatePolicy returns the configured policy for duplicates, or an empty string if no ledger is configured.
+func (p *Pipeline) duplicatePolicy() string {
+	if p.config.ledger == nil {
+		return ""
+	}
+	if p.config.duplicatePolicy == "" {
+		return DuplicateSkip
+	}
+	return p.config.duplicatePolicy
+}
+
+// checkDuplicateFile hashes the file at path and looks it up in the ledger. Returns the hash, or an empty string if
+// the ledger is not used, and whether the file should be skipped as a duplicate.
+func (p *Pipeline) checkDuplicateFile(path string) (string, bool) {
+	policy := p.duplicatePolicy()
+	if policy == "" {
+		return "", false
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot hash '%s' for the ledger: %v", path, err), ERROR)
+		return "", false
+	}
+	if policy == DuplicateForce {
+		return hash, false
+	}
+
+	seen, err := p.config.ledger.contains(ledgerFiles, hash)
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot read the ledger: %v", err), ERROR)
+		return hash, false
+	}
+	if !seen {
+		return hash, false
+	}
+	if policy == DuplicateWarn {
+		p.Logging(fmt.Sprintf("File '%s' was processed before, processing it again", path), WARNING)
+		return hash, false
+	}
+	p.Logging(fmt.Sprintf("File '%s' was processed before, skipping it", path), WARNING)
+	return hash, true
+}
+
+// recordFile records the hash of a successfully processed file in the ledger.
+func (p *Pipeline) recordFile(path, hash string) {
+	if hash == "" {
+		return
+	}
+	if err := p.config.ledger.record(ledgerFiles, filepath.Base(path), hash); err != nil {
+		p.Logging(fmt.Sprintf("Cannot record '%s' in the ledger: %v", path, err), ERROR)
+	}
+}
+
+// checkDuplicateRecord looks the FlowG record of a sample up in the ledger. Returns the hash of the record, or an
+// empty string if the ledger is not used, and whether the result was delivered before. Duplicates are not reported
+// under DuplicateForce.
+func (p *Pipeline) checkDuplicateRecord(record []string) (string, bool) {
+	policy := p.duplicatePolicy()
+	if policy == "" {
+		return "", false
+	}
+
+	hash := hashRecord(record)
+	if policy == DuplicateForce {
+		return hash, false
+	}
+
+	seen, err := p.config.ledger.contains(ledgerResults, hash)
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot read the ledger: %v", err), ERROR)
+		return hash, false
+	}
+	return hash, seen
+}
+
+// recordSamples records the hashes of the results delivered in the output file fileName in the ledger.
+func (p *Pipeline) recordSamples(fileName string, hashes []string) {
+	if len(hashes) == 0 {
+		return
+	}
+	if err := p.config.ledger.record(ledgerResults, fileName, hashes...); err != nil {
+		p.Logging(fmt.Sprintf("Cannot record the results of '%s' in the ledger: %v", fileName, err), ERROR)
+	}
+}
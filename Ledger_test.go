@@ -0,0 +1,180 @@
+package FlowG
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLedgerPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	l := newLedger(dir)
+	if err := l.record(ledgerFiles, "run1.csv", "aaa", "bbb", "aaa"); err != nil {
+		t.Fatalf("record returned error: %v", err)
+	}
+	if err := l.record(ledgerFiles, "run2.csv", "bbb"); err != nil {
+		t.Fatalf("record returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, ledgerFiles+".ledger"))
+	if err != nil {
+		t.Fatalf("Cannot read ledger file: %v", err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("Expected 2 ledger entries, got %d: %q", lines, content)
+	}
+
+	// A new ledger on the same directory, as after a restart, knows the recorded hashes
+	reloaded := newLedger(dir)
+	cases := []struct {
+		kind string
+		hash string
+		want bool
+	}{
+		{ledgerFiles, "aaa", true},
+		{ledgerFiles, "bbb", true},
+		{ledgerFiles, "ccc", false},
+		{ledgerResults, "aaa", false},
+	}
+	for _, c := range cases {
+		seen, err := reloaded.contains(c.kind, c.hash)
+		if err != nil {
+			t.Fatalf("contains returned error: %v", err)
+		}
+		if seen != c.want {
+			t.Errorf("contains(%q, %q): expected %v, got %v", c.kind, c.hash, c.want, seen)
+		}
+	}
+}
+
+func TestGlimsOutputDuplicates(t *testing.T) {
+	cases := []struct {
+		name       string
+		policy     string
+		wantOk     bool
+		wantOutput int
+	}{
+		{"Skip duplicates", DuplicateSkip, true, 1},
+		{"Warn about duplicates", DuplicateWarn, true, 2},
+		{"Force duplicates", DuplicateForce, true, 2},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logLvl:       CRITICAL,
+
+				ledger:          newLedger(t.TempDir()),
+				duplicatePolicy: c.policy,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			samples := []SampleStruct{
+				{Barcode: "Sample1", TestName: "Compound1", Result: ptrFloat64(1.5), InstrumentID: "Instrument1"},
+			}
+			if ok := GlimsOutput("first", samples); !ok {
+				t.Fatalf("First GlimsOutput failed")
+			}
+			// Give the second output file a different timestamp
+			time.Sleep(2 * time.Millisecond)
+			if ok := GlimsOutput("second", samples); ok != c.wantOk {
+				t.Errorf("Second GlimsOutput: expected %v, got %v", c.wantOk, ok)
+			}
+
+			outputFiles, _ := os.ReadDir(config.glimsDir)
+			if len(outputFiles) != c.wantOutput {
+				t.Errorf("Expected %d output files, got %d", c.wantOutput, len(outputFiles))
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateFile(t *testing.T) {
+	cases := []struct {
+		name          string
+		policy        string
+		record        bool
+		wantDuplicate bool
+	}{
+		{"New file", DuplicateSkip, false, false},
+		{"Duplicate skipped", DuplicateSkip, true, true},
+		{"Duplicate with warning", DuplicateWarn, true, false},
+		{"Duplicate forced", DuplicateForce, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				logDir:          t.TempDir(),
+				logLvl:          CRITICAL,
+				ledger:          newLedger(t.TempDir()),
+				duplicatePolicy: c.policy,
+			}
+			path := filepath.Join(t.TempDir(), "run.csv")
+			if err := os.WriteFile(path, []byte("Sample1;Compound1;1.5"), 0644); err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			p := defaultPipeline()
+			if c.record {
+				hash, err := hashFile(path)
+				if err != nil {
+					t.Fatalf("hashFile returned error: %v", err)
+				}
+				p.recordFile(path, hash)
+			}
+
+			hash, duplicate := p.checkDuplicateFile(path)
+			if hash == "" || duplicate != c.wantDuplicate {
+				t.Errorf("Expected duplicate %v with a hash, got %v (%q)", c.wantDuplicate, duplicate, hash)
+			}
+		})
+	}
+}
+
+func TestHashRecord(t *testing.T) {
+	sample := func(ct float64) SampleStruct {
+		return SampleStruct{Barcode: "Sample1", TestName: "Test1", ResultCT: &ct, InstrumentID: "Instrument1"}
+	}
+	cases := []struct {
+		name    string
+		formats map[string]NumberFormat
+		a, b    float64
+		want    bool
+	}{
+		{"Same value", nil, 27.456, 27.456, true},
+		{"Written the same", nil, 27.456, 27.4561, true},
+		{"Written differently", nil, 27.456, 27.466, false},
+		{"Differ in the 3rd decimal with 3 decimals", map[string]NumberFormat{"Test1": {Decimals: 3}}, 27.456, 27.459, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{numberFormats: c.formats}
+			p := defaultPipeline()
+			a, _ := p.record(sample(c.a))
+			b, _ := p.record(sample(c.b))
+			if same := hashRecord(a) == hashRecord(b); same != c.want {
+				t.Errorf("Expected equal hashes for %v and %v to be %v, got %v", c.a, c.b, c.want, same)
+			}
+		})
+	}
+}
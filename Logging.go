@@ -40,22 +40,27 @@ func GetLogLvLID(name string) (uint8, bool) {
 	return value, exists
 }
 
-// Logging logs a message with a specified severity level. Messages are written to a log file specific to the current date.
+// Logging logs a message with a specified severity level to the log of the default pipeline, see Pipeline.Logging.
 func Logging(msg string, lvl uint8) {
-	if config.logDir == "" {
+	defaultPipeline().Logging(msg, lvl)
+}
+
+// Logging logs a message with a specified severity level. Messages are written to a log file specific to the current date.
+func (p *Pipeline) Logging(msg string, lvl uint8) {
+	if p.config.logDir == "" {
 		panic("Logging path undefined")
 	}
-	if config.logLvl > 4 {
-		invalidLevel := config.logLvl
-		config.logLvl = 1
-		Logging(fmt.Sprintf("Loglevel was set to invalid level %d, defaulting to %s (%d)", invalidLevel, levelNames[config.logLvl], config.logLvl), WARNING)
+	if p.config.logLvl > 4 {
+		invalidLevel := p.config.logLvl
+		p.config.logLvl = 1
+		p.Logging(fmt.Sprintf("Loglevel was set to invalid level %d, defaulting to %s (%d)", invalidLevel, levelNames[p.config.logLvl], p.config.logLvl), WARNING)
 	}
-	if config.logLvl > lvl {
+	if p.config.logLvl > lvl {
 		return
 	}
 
 	// Set the log file name with today's date
-	logFileName := fmt.Sprintf("%s/%s_%s.txt", config.logDir, config.logPrefix, time.Now().Format("2006-01-02"))
+	logFileName := fmt.Sprintf("%s/%s_%s.txt", p.config.logDir, p.config.logPrefix, time.Now().Format("2006-01-02"))
 	file, err := os.OpenFile(logFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		// Handle the error silently (or print to stderr if needed)
@@ -74,7 +79,7 @@ func Logging(msg string, lvl uint8) {
 	if !ok {
 		logLevelName = levelNames[WARNING] // Default to WARNING if unknown
 		// Log an extra warning about the unknown level
-		Logging(fmt.Sprintf("Unknown log level %d used by application, defaulting to %s", lvl, logLevelName), WARNING)
+		p.Logging(fmt.Sprintf("Unknown log level %d used by application, defaulting to %s", lvl, logLevelName), WARNING)
 	}
 
 	// Get the current timestamp and format the log entry
@@ -0,0 +1,212 @@
+package FlowG
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MappingRule translates the code an instrument uses for an assay into a GLIMS TEST_ID. Instrument, Code and
+// Channel may contain the wildcards '*' and '?'. An empty Instrument or Channel matches any value.
+type MappingRule struct {
+	Instrument string `json:"instrument,omitempty"` // Matched against SampleStruct.InstrumentID
+	Code       string `json:"code"`                 // Matched against SampleStruct.TestName
+	Channel    string `json:"channel,omitempty"`    // Matched against SampleStruct.Channel
+	TestID     string `json:"testId"`               // The GLIMS TEST_ID written to the output
+}
+
+// matches reports whether the rule applies to the given instrument, code and channel.
+func (r MappingRule) matches(instrument, code, channel string) bool {
+	return matchWildcard(r.Instrument, instrument) && matchWildcard(r.Code, code) && matchWildcard(r.Channel, channel)
+}
+
+// matchWildcard reports whether value matches pattern, where an empty pattern matches any value. Only '*' (any
+// sequence of characters, including '/') and '?' (any single character) are special, as assay names are free text
+// that may contain characters such as '/', '[' or '\\'.
+func matchWildcard(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	// Match character by character, backtracking to the last '*' on a mismatch
+	p, v := []rune(pattern), []rune(value)
+	i, j := 0, 0
+	star, mark := -1, 0
+	for j < len(v) {
+		switch {
+		case i < len(p) && p[i] == '*':
+			star, mark = i, j
+			i++
+		case i < len(p) && (p[i] == '?' || p[i] == v[j]):
+			i++
+			j++
+		case star >= 0:
+			i = star + 1
+			mark++
+			j = mark
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// TestMapping translates instrument assay codes into GLIMS TEST_IDs before GlimsOutput writes them, see the
+// 'testMapping' config key. The first matching rule wins, so specific rules must precede rules with wildcards. A
+// mapping loaded from a file is reloaded when the file changes.
+type TestMapping struct {
+	path string
+
+	mu      sync.Mutex
+	rules   []MappingRule
+	modTime time.Time
+	size    int64
+}
+
+// NewTestMapping creates a TestMapping from a list of rules, validating each rule.
+func NewTestMapping(rules []MappingRule) (*TestMapping, error) {
+	if err := validateMappingRules(rules); err != nil {
+		return nil, err
+	}
+	return &TestMapping{rules: rules}, nil
+}
+
+// LoadTestMapping reads a TestMapping from a JSON or CSV file, depending on its extension. A JSON mapping is a list
+// of MappingRule objects. A CSV mapping has a header with the columns instrument, code, channel and testId,
+// separated by semicolons or commas. The file is checked for changes every time GlimsOutput is called.
+func LoadTestMapping(path string) (*TestMapping, error) {
+	m := &TestMapping{path: path}
+	if _, err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reload reads the mapping file again if it changed since it was last read. On failure the previous rules are
+// kept. Returns whether the rules were reloaded.
+func (m *TestMapping) reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return false, fmt.Errorf("cannot read test mapping '%s': %w", m.path, err)
+	}
+	if info.ModTime().Equal(m.modTime) && info.Size() == m.size {
+		return false, nil
+	}
+
+	rules, err := readMappingFile(m.path)
+	if err != nil {
+		return false, fmt.Errorf("cannot read test mapping '%s': %w", m.path, err)
+	}
+	if err = validateMappingRules(rules); err != nil {
+		return false, fmt.Errorf("invalid test mapping '%s': %w", m.path, err)
+	}
+	m.rules = rules
+	m.modTime = info.ModTime()
+	m.size = info.Size()
+	return true, nil
+}
+
+// readMappingFile reads the rules of a JSON or CSV mapping file.
+func readMappingFile(path string) ([]MappingRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []MappingRule
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(file).Decode(&rules)
+	case ".csv", ".txt":
+		rules, err = readMappingCSV(file)
+	default:
+		err = fmt.Errorf("unsupported format '%s', use .json or .csv", filepath.Ext(path))
+	}
+	return rules, err
+}
+
+// readMappingCSV reads the rules of a CSV mapping.
+func readMappingCSV(r io.Reader) ([]MappingRule, error) {
+	table, err := readCSVTable(r, "code", "testId")
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]MappingRule, 0, len(table.rows))
+	for _, row := range table.rows {
+		rules = append(rules, MappingRule{
+			Instrument: table.field(row, "instrument"),
+			Code:       table.field(row, "code"),
+			Channel:    table.field(row, "channel"),
+			TestID:     table.field(row, "testId"),
+		})
+	}
+	return rules, nil
+}
+
+// validateMappingRules checks that every rule has a code and a TEST_ID.
+func validateMappingRules(rules []MappingRule) error {
+	for i, rule := range rules {
+		if rule.Code == "" || rule.TestID == "" {
+			return fmt.Errorf("rule %d requires a code and a testId", i+1)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the GLIMS TEST_ID for the code of an assay of instrument, and whether a rule matched.
+func (m *TestMapping) Lookup(instrument, code, channel string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, rule := range m.rules {
+		if rule.matches(instrument, code, channel) {
+			return rule.TestID, true
+		}
+	}
+	return "", false
+}
+
+// applyMapping replaces the TestName of the samples by their GLIMS TEST_ID according to config.testMapping,
+// reloading the mapping file first if it changed. Samples without a matching rule keep their TestName, and are
+// reported as warnings.
+func (p *Pipeline) applyMapping(samples []SampleStruct, report *OutputReport) []SampleStruct {
+	mapping := p.config.testMapping
+	if mapping == nil {
+		return samples
+	}
+
+	reloaded, err := mapping.reload()
+	if err != nil {
+		p.Logging(fmt.Sprintf("Keeping the previous test mapping: %v", err), ERROR)
+	} else if reloaded && mapping.path != "" {
+		p.Logging(fmt.Sprintf("Loaded test mapping '%s'", mapping.path), INFO)
+	}
+
+	mapped := make([]SampleStruct, len(samples))
+	for i, sample := range samples {
+		if testID, ok := mapping.Lookup(sample.InstrumentID, sample.TestName, sample.Channel); ok {
+			sample.TestName = testID
+		} else if sample.TestName != "" {
+			report.warn(p, fmt.Sprintf("No test mapping for code '%s' (channel '%s') of instrument '%s'",
+				sample.TestName, sample.Channel, sample.InstrumentID))
+		}
+		mapped[i] = sample
+	}
+	return mapped
+}
@@ -0,0 +1,214 @@
+package FlowG
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTestMapping(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"CSV with semicolons", "mapping.csv", "instrument;code;channel;testId\nQS5-01;SC2;N1;SARS-CoV-2\n", ""},
+		{"CSV with commas", "mapping.csv", "code, testId\nSC2, SARS-CoV-2\n", ""},
+		{"JSON", "mapping.json", `[{"instrument": "QS5-01", "code": "SC2", "channel": "N1", "testId": "SARS-CoV-2"}]`, ""},
+		{"CSV without testId", "mapping.csv", "instrument;code\nQS5-01;SC2\n", "missing testId column"},
+		{"Rule without testId", "mapping.json", `[{"code": "SC2"}]`, "rule 1 requires a code and a testId"},
+		{"Unsupported format", "mapping.xml", "<mapping/>", "unsupported format '.xml'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), c.file)
+			if err := os.WriteFile(path, []byte(c.content), 0644); err != nil {
+				t.Fatalf("Error creating mapping: %v", err)
+			}
+
+			mapping, err := LoadTestMapping(path)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTestMapping returned error: %v", err)
+			}
+			if testID, ok := mapping.Lookup("QS5-01", "SC2", "N1"); !ok || testID != "SARS-CoV-2" {
+				t.Errorf("Expected SARS-CoV-2, got %q (%v)", testID, ok)
+			}
+		})
+	}
+}
+
+func TestTestMappingLookup(t *testing.T) {
+	mapping, err := NewTestMapping([]MappingRule{
+		{Instrument: "QS5-01", Code: "FLU", Channel: "FAM", TestID: "INFA-QS5-01"},
+		{Instrument: "QS5-*", Code: "FLU", Channel: "FAM", TestID: "INFA"},
+		{Instrument: "QS5-*", Code: "FLU", Channel: "VIC", TestID: "INFB"},
+		{Code: "HBV?", TestID: "HBV-DNA"},
+		{Code: "HIV*", TestID: "HIV-AB"},
+		{Code: "IgG [S1]", TestID: "SARS-IGG"},
+	})
+	if err != nil {
+		t.Fatalf("NewTestMapping returned error: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		instrument string
+		code       string
+		channel    string
+		wantTestID string
+		wantOk     bool
+	}{
+		{"Exact rule before wildcard", "QS5-01", "FLU", "FAM", "INFA-QS5-01", true},
+		{"Wildcard instrument", "QS5-02", "FLU", "FAM", "INFA", true},
+		{"Other channel", "QS5-02", "FLU", "VIC", "INFB", true},
+		{"Unknown channel", "QS5-02", "FLU", "ROX", "", false},
+		{"Any instrument and channel", "Cobas", "HBV1", "", "HBV-DNA", true},
+		{"Unknown code", "QS5-01", "RSV", "FAM", "", false},
+		{"Wildcard across a slash", "Alinity", "HIV-1/2 Ab", "", "HIV-AB", true},
+		{"Brackets are literal", "Alinity", "IgG [S1]", "", "SARS-IGG", true},
+		{"Brackets are not a character class", "Alinity", "IgG S", "", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testID, ok := mapping.Lookup(c.instrument, c.code, c.channel)
+			if testID != c.wantTestID || ok != c.wantOk {
+				t.Errorf("Expected %q (%v), got %q (%v)", c.wantTestID, c.wantOk, testID, ok)
+			}
+		})
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"", "anything", true},
+		{"FLU", "FLU", true},
+		{"FLU", "FLUB", false},
+		{"*", "", true},
+		{"QS5-*", "QS5-01", true},
+		{"QS5-*", "QS6-01", false},
+		{"*-01", "QS5-01", true},
+		{"H*V*Ab", "HIV-1/2 Ab", true},
+		{"H*V*Ab", "HIV-1/2 Ag", false},
+		{"SC?", "SC2", true},
+		{"SC?", "SC", false},
+		{"µ?", "µg", true},
+		{`IgG [S1]\`, `IgG [S1]\`, true},
+	}
+
+	for _, c := range cases {
+		if got := matchWildcard(c.pattern, c.value); got != c.want {
+			t.Errorf("matchWildcard(%q, %q): expected %v, got %v", c.pattern, c.value, c.want, got)
+		}
+	}
+}
+
+func TestTestMappingReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.csv")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing mapping: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Error setting modification time: %v", err)
+		}
+	}
+
+	now := time.Now()
+	write("code;testId\nSC2;SARS-CoV-2\n", now.Add(-2*time.Minute))
+	mapping, err := LoadTestMapping(path)
+	if err != nil {
+		t.Fatalf("LoadTestMapping returned error: %v", err)
+	}
+
+	// An unchanged file is not read again
+	if reloaded, err := mapping.reload(); reloaded || err != nil {
+		t.Errorf("Expected no reload of an unchanged file, got %v, %v", reloaded, err)
+	}
+
+	write("code;testId\nSC2;COVID-19\n", now.Add(-time.Minute))
+	if reloaded, err := mapping.reload(); !reloaded || err != nil {
+		t.Errorf("Expected a reload of the changed file, got %v, %v", reloaded, err)
+	}
+	if testID, _ := mapping.Lookup("", "SC2", ""); testID != "COVID-19" {
+		t.Errorf("Expected the reloaded rule COVID-19, got %q", testID)
+	}
+
+	// A broken file keeps the previous rules
+	write("code;testId\nSC2;\n", now)
+	if _, err := mapping.reload(); err == nil {
+		t.Error("Expected an error reloading an invalid mapping")
+	}
+	if testID, _ := mapping.Lookup("", "SC2", ""); testID != "COVID-19" {
+		t.Errorf("Expected the previous rule COVID-19, got %q", testID)
+	}
+}
+
+func TestGlimsOutputMapping(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logLvl:       CRITICAL,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	config.testMapping, err = NewTestMapping([]MappingRule{
+		{Instrument: "QS5-*", Code: "FLU", Channel: "FAM", TestID: "INFA"},
+		{Instrument: "QS5-*", Code: "FLU", Channel: "VIC", TestID: "INFB"},
+	})
+	if err != nil {
+		t.Fatalf("NewTestMapping returned error: %v", err)
+	}
+
+	samples := []SampleStruct{
+		{Barcode: "Sample1", TestName: "FLU", Channel: "FAM", ResultText: "POS", InstrumentID: "QS5-01"},
+		{Barcode: "Sample1", TestName: "FLU", Channel: "VIC", ResultText: "NEG", InstrumentID: "QS5-01"},
+		{Barcode: "Sample1", TestName: "RSV", Channel: "FAM", ResultText: "NEG", InstrumentID: "QS5-01"},
+	}
+	report, err := GlimsOutputReport("output", samples)
+	if err != nil {
+		t.Fatalf("GlimsOutputReport returned error: %v", err)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "code 'RSV'") {
+		t.Errorf("Expected a warning about the unmapped code RSV, got %q", report.Warnings)
+	}
+
+	content, err := os.ReadFile(report.File)
+	if err != nil {
+		t.Fatalf("Error reading output: %v", err)
+	}
+	want := "Sample1;INFA;;POS;;;QS5-01\nSample1;INFB;;NEG;;;QS5-01\nSample1;RSV;;NEG;;;QS5-01\n"
+	if string(content) != want {
+		t.Errorf("Expected output %q, got %q", want, content)
+	}
+	if samples[0].TestName != "FLU" {
+		t.Errorf("Expected the SampleList of the caller to be left unchanged, got %q", samples[0].TestName)
+	}
+}
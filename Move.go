@@ -0,0 +1,131 @@
+package FlowG
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// errorNotSameDevice is the Windows error returned when renaming a file to another volume
+const errorNotSameDevice = syscall.Errno(17)
+
+// rename renames a file, it is a variable so tests can simulate moves across filesystems
+var rename = os.Rename
+
+// moveAcross moves the file at src to dst. When src and dst are on different filesystems, where os.Rename fails, the
+// file is copied to dst, synced to disk and verified against src by size and SHA-256 hash before src is removed.
+func moveAcross(src, dst string) error {
+	err := rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	return copyVerifyRemove(src, dst)
+}
+
+// isCrossDevice reports whether err is the result of renaming a file to another filesystem.
+func isCrossDevice(err error) bool {
+	if errors.Is(err, syscall.EXDEV) {
+		return true
+	}
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && errno == errorNotSameDevice
+}
+
+// copyVerifyRemove copies src to a hidden temporary file next to dst, syncs and verifies it, renames it to dst and
+// removes src. On failure dst is not created and src is left in place.
+func copyVerifyRemove(src, dst string) error {
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp")
+	if err := copySynced(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("cannot copy '%s' to '%s': %w", src, dst, err)
+	}
+	if err := verifyCopy(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("cannot copy '%s' to '%s': %w", src, dst, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(dst))
+
+	if err := os.Remove(src); err != nil {
+		// Keep a single copy of the file, so it is not processed twice
+		_ = os.Remove(dst)
+		return fmt.Errorf("copied '%s' to '%s', but cannot remove the original: %w", src, dst, err)
+	}
+	return nil
+}
+
+// copySynced copies src to a new file dst, preserving its modification time, and syncs dst to disk.
+func copySynced(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// verifyCopy checks that dst has the same size and SHA-256 hash as src.
+func verifyCopy(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return fmt.Errorf("copy has %d bytes instead of %d", dstInfo.Size(), srcInfo.Size())
+	}
+
+	srcHash, err := hashFile(src)
+	if err != nil {
+		return err
+	}
+	dstHash, err := hashFile(dst)
+	if err != nil {
+		return err
+	}
+	if srcHash != dstHash {
+		return errors.New("copy does not match the original")
+	}
+	return nil
+}
+
+// syncDir syncs a directory to disk, so a renamed file survives a crash. Not all platforms support this, so errors
+// are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,77 @@
+package FlowG
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMoveAcross(t *testing.T) {
+	crossDevice := func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	cases := []struct {
+		name        string
+		rename      func(string, string) error
+		createSrc   bool
+		wantErr     bool
+		wantMoved   bool
+		wantCrossed bool
+	}{
+		{"Same filesystem", os.Rename, true, false, true, false},
+		{"Other filesystem", crossDevice, true, false, true, true},
+		{"Missing source on other filesystem", crossDevice, false, true, false, true},
+		{"Missing source", os.Rename, false, true, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func(original func(string, string) error) { rename = original }(rename)
+			rename = c.rename
+
+			dir := t.TempDir()
+			src := filepath.Join(dir, "run.csv")
+			dst := filepath.Join(dir, "archive", "20240101120000000_run.csv")
+			if err := os.Mkdir(filepath.Dir(dst), os.ModePerm); err != nil {
+				t.Fatalf("Error creating destination: %v", err)
+			}
+			modTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+			if c.createSrc {
+				if err := os.WriteFile(src, []byte("Sample1;Compound1;1.5"), 0644); err != nil {
+					t.Fatalf("Error creating test file: %v", err)
+				}
+				if err := os.Chtimes(src, modTime, modTime); err != nil {
+					t.Fatalf("Error setting modification time: %v", err)
+				}
+			}
+
+			err := moveAcross(src, dst)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("moveAcross returned error %v, wanted error %v", err, c.wantErr)
+			}
+			if c.wantErr && !c.wantCrossed && !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected the rename error to be returned, got %v", err)
+			}
+
+			_, srcErr := os.Stat(src)
+			info, dstErr := os.Stat(dst)
+			if c.wantMoved {
+				if !errors.Is(srcErr, os.ErrNotExist) || dstErr != nil {
+					t.Errorf("Expected the file to be moved, source: %v, destination: %v", srcErr, dstErr)
+				} else if !info.ModTime().Equal(modTime) {
+					t.Errorf("Expected modification time %s, got %s", modTime, info.ModTime())
+				}
+			} else if dstErr == nil {
+				t.Errorf("Expected no file at the destination")
+			}
+
+			leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(dst), ".*"))
+			if len(leftovers) != 0 {
+				t.Errorf("Expected no temporary files, got %v", leftovers)
+			}
+		})
+	}
+}
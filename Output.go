@@ -1,7 +1,7 @@
 package FlowG
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,88 +18,166 @@ type SampleStruct struct {
 	ResultINT         *float64
 	ResultCT          *float64
 	InstrumentID      string
+
+	ResultText       string     // Qualitative result such as POS, NEG or INVALID, written when Result is nil
+	ResultComparator Comparator // Qualifies Result, e.g. GreaterThan for '>1000'
+	Channel          string     // Channel or target of a multiplex assay, used by the 'testMapping' config key
+
+	// Optional fields, only written when their column is part of the 'outputColumns' config key
+	Unit         string    // Unit of the result, such as 'copies/mL'
+	Flags        string    // Abnormal flags, such as 'H' or 'L'
+	Comment      string    // Comment of the technician
+	AnalysisTime time.Time // Moment the instrument analysed the sample, see 'analysisTimeLayout'
+	OperatorID   string    // ID of the operator who ran the analysis
+}
+
+// ErrNoValidSamples is returned by GlimsOutputReport when none of the samples could be written.
+var ErrNoValidSamples = errors.New("no valid samples to output")
+
+// OutputReport describes the outcome of GlimsOutputReport.
+type OutputReport struct {
+	File       string      // Path of the written output file, empty if no file was written
+	Rows       int         // Number of rows written to File
+	Rejections []Rejection // Samples that were not written because they are invalid
+	Duplicates int         // Number of samples left out because they were delivered before, see 'duplicatePolicy'
+	Warnings   []string    // Problems that did not prevent writing a sample
+}
+
+// Rejection describes a sample that GlimsOutputReport did not write.
+type Rejection struct {
+	Index   int    // Index of the sample in the SampleList
+	Barcode string // Barcode of the sample, possibly empty
+	Reason  string // Why the sample was rejected
+}
+
+// warn adds a warning to the report and logs it.
+func (r *OutputReport) warn(p *Pipeline, msg string) {
+	r.Warnings = append(r.Warnings, msg)
+	p.Logging(msg, WARNING)
+}
+
+// reject adds a rejected sample to the report and logs it.
+func (r *OutputReport) reject(p *Pipeline, index int, barcode string, reason string) {
+	r.Rejections = append(r.Rejections, Rejection{Index: index, Barcode: barcode, Reason: reason})
+	p.Logging(fmt.Sprintf("GlimsOutput rejected sample %d (barcode '%s'): %s", index, barcode, reason), WARNING)
 }
 
-// GlimsOutput processes a list of samples and outputs them to a CSV file with the provided filename according to the FlowG standard.
+// GlimsOutput processes a list of samples and outputs them to the glimsDir of the default pipeline, see
+// Pipeline.GlimsOutput.
 func GlimsOutput(FileName string, SampleList []SampleStruct) bool {
+	return defaultPipeline().GlimsOutput(FileName, SampleList)
+}
+
+// GlimsOutput processes a list of samples and outputs them to a CSV file with the provided filename according to the
+// FlowG standard. Returns false if no file could be written, see GlimsOutputReport for the details.
+func (p *Pipeline) GlimsOutput(FileName string, SampleList []SampleStruct) bool {
+	_, err := p.GlimsOutputReport(FileName, SampleList)
+	return err == nil
+}
+
+// GlimsOutputReport processes a list of samples and outputs them to the glimsDir of the default pipeline, see
+// Pipeline.GlimsOutputReport.
+func GlimsOutputReport(FileName string, SampleList []SampleStruct) (OutputReport, error) {
+	return defaultPipeline().GlimsOutputReport(FileName, SampleList)
+}
+
+// GlimsOutputReport behaves like GlimsOutput, but reports which samples were written and which were rejected and
+// why, so the caller can decide whether a partial delivery is acceptable. Returns ErrNoValidSamples if none of the
+// samples could be written, and a nil error without a file if all samples were delivered before.
+func (p *Pipeline) GlimsOutputReport(FileName string, SampleList []SampleStruct) (OutputReport, error) {
+	var report OutputReport
 	if len(FileName) == 0 {
-		Logging("Invalid or no FileName was given to GlimsOutput, doing nothing", ERROR)
-		return false
+		p.Logging("Invalid or no FileName was given to GlimsOutput, doing nothing", ERROR)
+		return report, errors.New("no FileName was given")
 	}
 	if len(SampleList) == 0 {
-		Logging("Empty SampleList was given to GlimsOutput, doing nothing", WARNING)
-		return false
+		p.Logging("Empty SampleList was given to GlimsOutput, doing nothing", WARNING)
+		return report, errors.New("empty SampleList was given")
 	}
-
 	timestamp := strings.ReplaceAll(time.Now().Format("20060102150405.000"), ".", "")
 	FileName = fmt.Sprintf("input.%s_%s.txt", timestamp, FileName)
 
-	file, err := os.Create(filepath.Join(config.glimsDir, FileName))
+	// Rows are written to a hidden temporary file, which is only renamed to its final name once it is complete, so
+	// GLIMS never picks up a partially written or empty file
+	tmpPath := filepath.Join(p.config.glimsDir, "."+FileName+".tmp")
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
-		Logging(fmt.Sprintf("Cannot create Glims-output file '%s': %v", FileName, err), ERROR)
-		return false
+		p.Logging(fmt.Sprintf("Cannot create Glims-output file '%s': %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot create Glims-output file '%s': %w", FileName, err)
 	}
-	defer func(file *os.File) {
-		err = file.Close()
-		if err != nil {
-			Logging(fmt.Sprintf("Cannot close Glims-output file '%s': %v", FileName, err), ERROR)
+	completed := false
+	defer func() {
+		if completed {
 			return
 		}
-		Logging(fmt.Sprintf("GlimsOutput successfully closed file '%s'", FileName), DEBUG)
-	}(file)
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-	writer.Comma = ';'
-
-	successCounter := 0
-	for _, sample := range SampleList {
-		Logging(fmt.Sprintf("GlimsOutput - Processing sample: %v", sample), DEBUG)
-		if len(sample.Barcode) == 0 || len(sample.TestName) == 0 || len(sample.InstrumentID) == 0 {
-			Logging("Incomplete sample send to GlimsOutput, skipping", WARNING)
-			continue
+		_ = file.Close()
+		if err := os.Remove(tmpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			p.Logging(fmt.Sprintf("Cannot remove temporary Glims-output file '%s': %v", tmpPath, err), WARNING)
 		}
+	}()
 
-		record := []string{
-			sample.Barcode,                    // Column 01, SPECIMEN_ID
-			sample.TestName,                   // Column 02, TEST_ID
-			sample.IsolationSequence,          // Column 03, ISOLATION_SEQUENCE
-			convertToString(sample.Result),    // Column 04, RESULT
-			convertToString(sample.ResultINT), // Column 05, RSLTTYPE_INT
-			convertToString(sample.ResultCT),  // Column 06, RSLTTYPE_CT
-			sample.InstrumentID,               // Column 07, INSTRUMENT_ID
-		}
-		if err = writer.Write(record); err != nil {
-			Logging(fmt.Sprintf("Cannot write to Glims-output file '%s': %v", FileName, err), ERROR)
-			return false
+	encoder := p.NewEncoder(file)
+	err = encoder.Encode(SampleList...)
+	report = encoder.Report()
+	if err != nil {
+		p.Logging(fmt.Sprintf("Cannot write to Glims-output file '%s': %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot write to Glims-output file '%s': %w", FileName, err)
+	}
+
+	// Do not deliver a file if there were no samples successfully added to it
+	if report.Rows == 0 {
+		p.Logging(fmt.Sprintf("The file '%s' didn't contain any valid sampled. No Glims-output was written", FileName), INFO)
+		// Results that were all delivered before are not an error, there was simply nothing new to deliver
+		if report.Duplicates > 0 && len(report.Rejections) == 0 {
+			return report, nil
 		}
+		return report, ErrNoValidSamples
+	}
 
-		successCounter++
-		Logging(fmt.Sprintf("GlimsOutput - Sample '%s' was processed correcly", sample.Barcode), DEBUG)
+	if err = encoder.Flush(); err != nil {
+		p.Logging(fmt.Sprintf("Cannot write to Glims-output file '%s': %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot write to Glims-output file '%s': %w", FileName, err)
+	}
+	if err = file.Sync(); err != nil {
+		p.Logging(fmt.Sprintf("Cannot sync Glims-output file '%s' to disk: %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot sync Glims-output file '%s' to disk: %w", FileName, err)
 	}
+	if err = file.Close(); err != nil {
+		p.Logging(fmt.Sprintf("Cannot close Glims-output file '%s': %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot close Glims-output file '%s': %w", FileName, err)
+	}
+	destPath := filepath.Join(p.config.glimsDir, FileName)
+	if err = os.Rename(tmpPath, destPath); err != nil {
+		p.Logging(fmt.Sprintf("Cannot deliver Glims-output file '%s': %v", FileName, err), ERROR)
+		return report, fmt.Errorf("cannot deliver Glims-output file '%s': %w", FileName, err)
+	}
+	completed = true
+	report.File = destPath
+	syncDir(p.config.glimsDir)
+	p.Logging(fmt.Sprintf("GlimsOutput successfully delivered file '%s'", FileName), DEBUG)
 
-	// Delete the outputfile if there were no samples successfully added to it
-	if successCounter == 0 {
-		// Force a file closure
-		err = file.Close()
-		if err != nil {
-			Logging(fmt.Sprintf("Cannot close Glims-output file '%s': %v", FileName, err), ERROR)
-			return false
-		}
-		Logging(fmt.Sprintf("GlimsOutput successfully closed file '%s'", FileName), DEBUG)
+	// Only record the results in the ledger once they are delivered
+	encoder.Commit(FileName)
+	return report, nil
+}
 
-		// Delete file
-		err = os.Remove(filepath.Join(config.glimsDir, FileName))
-		Logging(fmt.Sprintf("The file '%s' didn't contain any valid sampled. The empty Glims-output was deleted", FileName), INFO)
-		if err != nil {
-			Logging(fmt.Sprintf("Cannot remove empty/obsolete Glims-output file '%s': %v", FileName, err), WARNING)
-		}
-		return false
+// missingFields returns the names of the required fields that are empty in sample.
+func missingFields(sample SampleStruct) []string {
+	var missing []string
+	if len(sample.Barcode) == 0 {
+		missing = append(missing, "Barcode")
+	}
+	if len(sample.TestName) == 0 {
+		missing = append(missing, "TestName")
+	}
+	if len(sample.InstrumentID) == 0 {
+		missing = append(missing, "InstrumentID")
 	}
-	return true
+	return missing
 }
 
-// convertToString converts an *integer or *float64 value to a string. Uses pointers to be capable of handling nil
+// convertToString converts an *integer or *float64 value to a string, formatting a float64 with DefaultNumberFormat.
+// Uses pointers to be capable of handling nil
 func convertToString[T *int | *float64](value T) string {
 	switch v := any(value).(type) {
 	case *int:
@@ -111,7 +189,7 @@ func convertToString[T *int | *float64](value T) string {
 		if v == nil {
 			return ""
 		}
-		return strconv.FormatFloat(*v, 'f', 2, 64)
+		return DefaultNumberFormat.Format(*v)
 	default:
 		Logging(fmt.Sprintf("Cannot convert value to T. Got type '%T'", any(value)), ERROR)
 		return ""
@@ -1,7 +1,9 @@
 package FlowG
 
 import (
+	"errors"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -150,6 +152,119 @@ func TestGlimsOutput(t *testing.T) {
 	}
 }
 
+func TestGlimsOutputAtomic(t *testing.T) {
+	cases := []struct {
+		name       string
+		SampleList []SampleStruct
+		expectOk   bool
+		wantFiles  int
+	}{
+		{"Complete output", []SampleStruct{{Barcode: "Sample1", TestName: "Compound1", InstrumentID: "Instrument1"}}, true, 1},
+		{"No valid samples", []SampleStruct{{TestName: "Compound1", InstrumentID: "Instrument1"}}, false, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logLvl:       CRITICAL,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			if ok := GlimsOutput("output", c.SampleList); ok != c.expectOk {
+				t.Errorf("Unexpected status, expected %v, got %v", c.expectOk, ok)
+			}
+
+			// Only completed output files may be visible, temporary files are renamed or removed
+			outputFiles, _ := os.ReadDir(config.glimsDir)
+			if len(outputFiles) != c.wantFiles {
+				t.Fatalf("Expected %d files in glimsDir, got %v", c.wantFiles, outputFiles)
+			}
+			for _, file := range outputFiles {
+				if !strings.HasPrefix(file.Name(), "input.") || !strings.HasSuffix(file.Name(), "_output.txt") {
+					t.Errorf("Unexpected file '%s' in glimsDir", file.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestGlimsOutputReport(t *testing.T) {
+	valid := SampleStruct{Barcode: "Sample1", TestName: "Compound1", Result: ptrFloat64(1.5), InstrumentID: "Instrument1"}
+	cases := []struct {
+		name           string
+		FileName       string
+		SampleList     []SampleStruct
+		wantErr        error
+		wantRows       int
+		wantRejections []Rejection
+	}{
+		{"No fileName", "", []SampleStruct{valid}, errors.New("no FileName was given"), 0, nil},
+		{"All samples valid", "output", []SampleStruct{valid, valid}, nil, 2, nil},
+		{"Partially valid", "output", []SampleStruct{valid, {Barcode: "Sample2", TestName: "Compound1"}}, nil, 1,
+			[]Rejection{{Index: 1, Barcode: "Sample2", Reason: "missing InstrumentID"}}},
+		{"No valid samples", "output", []SampleStruct{{Result: ptrFloat64(1.5)}}, ErrNoValidSamples, 0,
+			[]Rejection{{Index: 0, Reason: "missing Barcode, TestName, InstrumentID"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logLvl:       CRITICAL,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			report, err := GlimsOutputReport(c.FileName, c.SampleList)
+			if (err == nil) != (c.wantErr == nil) || (err != nil && err.Error() != c.wantErr.Error()) {
+				t.Errorf("Expected error %v, got %v", c.wantErr, err)
+			}
+			if report.Rows != c.wantRows {
+				t.Errorf("Expected %d rows, got %d", c.wantRows, report.Rows)
+			}
+			if !reflect.DeepEqual(report.Rejections, c.wantRejections) {
+				t.Errorf("Expected rejections %+v, got %+v", c.wantRejections, report.Rejections)
+			}
+			if c.wantRows > 0 {
+				content, err := os.ReadFile(report.File)
+				if err != nil || strings.Count(string(content), "\n") != c.wantRows {
+					t.Errorf("Expected %d rows in '%s', got %q (%v)", c.wantRows, report.File, content, err)
+				}
+			} else if report.File != "" {
+				t.Errorf("Expected no output file, got '%s'", report.File)
+			}
+		})
+	}
+}
+
 func TestConvertToString(t *testing.T) {
 	// `int` tests
 	intCases := []struct {
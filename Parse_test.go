@@ -0,0 +1,159 @@
+package FlowG
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFlowGRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		columns []Column
+		samples []SampleStruct
+	}{
+		{"Default columns", nil, []SampleStruct{
+			{Barcode: "Sample1", TestName: "Test1", IsolationSequence: "1", Result: ptrFloat64(27.46), ResultINT: ptrFloat64(1), ResultCT: ptrFloat64(31.5), InstrumentID: "Instrument1"},
+			{Barcode: "Sample2", TestName: "Test1", Result: ptrFloat64(-0.5), InstrumentID: "Instrument1"},
+			{Barcode: "Sample3", TestName: "Test2", ResultText: "POS", InstrumentID: "Instrument1"},
+			{Barcode: "Sample4", TestName: "Test2", Result: ptrFloat64(1000), ResultComparator: GreaterThan, InstrumentID: "Instrument1"},
+			{Barcode: "Sample5", TestName: "Test2", Result: ptrFloat64(0.5), ResultComparator: LessOrEqual, InstrumentID: "Instrument1"},
+			{Barcode: "Sample6", TestName: "Test2", InstrumentID: "Instrument1"},
+		}},
+		{"Extended columns", []Column{ColumnInstrumentID, ColumnSpecimenID, ColumnTestID, ColumnResult, ColumnUnit, ColumnFlags, ColumnComment, ColumnAnalysisTime, ColumnOperatorID}, []SampleStruct{
+			{Barcode: "Sample1", TestName: "Test1", Result: ptrFloat64(1.25), InstrumentID: "Instrument1", Unit: "copies/mL", Flags: "H", Comment: "Re-run, hemolytic",
+				AnalysisTime: time.Date(2026, 10, 17, 9, 30, 15, 0, time.Local), OperatorID: "JD"},
+			{Barcode: "Sample2", TestName: "Test2", ResultText: "INVALID", InstrumentID: "Instrument1"},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logLvl:       CRITICAL,
+
+				outputColumns: c.columns,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			report, err := GlimsOutputReport("output", c.samples)
+			if err != nil {
+				t.Fatalf("GlimsOutputReport returned error: %v", err)
+			}
+			samples, err := ParseFlowGFile(report.File)
+			if err != nil {
+				t.Fatalf("ParseFlowGFile returned error: %v", err)
+			}
+			if !reflect.DeepEqual(samples, c.samples) {
+				t.Errorf("Expected samples %+v, got %+v", c.samples, samples)
+			}
+		})
+	}
+}
+
+func TestParseFlowGRoundTripNormalized(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logLvl:       CRITICAL,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	// Text results that would be read back as a number are written as one, numbers that cannot be read back are
+	// rejected
+	samples := []SampleStruct{
+		{Barcode: "Sample1", TestName: "Test1", ResultText: "<<5", InstrumentID: "Instrument1"},
+		{Barcode: "Sample2", TestName: "Test1", ResultText: "1000", InstrumentID: "Instrument1"},
+		{Barcode: "Sample3", TestName: "Test1", ResultText: ">5", InstrumentID: "Instrument1"},
+		{Barcode: "Sample4", TestName: "Test1", ResultText: "POS ", InstrumentID: "Instrument1"},
+		{Barcode: "Sample5", TestName: "Test1", Result: ptrFloat64(math.NaN()), InstrumentID: "Instrument1"},
+		{Barcode: "Sample6", TestName: "Test1", Result: ptrFloat64(1), ResultCT: ptrFloat64(math.Inf(-1)), InstrumentID: "Instrument1"},
+	}
+	want := []SampleStruct{
+		{Barcode: "Sample1", TestName: "Test1", ResultText: "<<5", InstrumentID: "Instrument1"},
+		{Barcode: "Sample2", TestName: "Test1", Result: ptrFloat64(1000), InstrumentID: "Instrument1"},
+		{Barcode: "Sample3", TestName: "Test1", Result: ptrFloat64(5), ResultComparator: GreaterThan, InstrumentID: "Instrument1"},
+		{Barcode: "Sample4", TestName: "Test1", ResultText: "POS", InstrumentID: "Instrument1"},
+	}
+	report, err := GlimsOutputReport("output", samples)
+	if err != nil {
+		t.Fatalf("GlimsOutputReport returned error: %v", err)
+	}
+	if report.Rows != len(want) || len(report.Rejections) != len(samples)-len(want) {
+		t.Errorf("Expected %d rows and %d rejections, got %+v", len(want), len(samples)-len(want), report)
+	}
+
+	parsed, err := ParseFlowGFile(report.File)
+	if err != nil {
+		t.Fatalf("ParseFlowGFile returned error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("Expected samples %+v, got %+v", want, parsed)
+	}
+}
+
+func TestParseFlowG(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    []SampleStruct
+		wantErr string
+	}{
+		{"Empty file", "", nil, ""},
+		{"CRLF line endings", "Sample1;Test1;;NEG;;;Instrument1\r\n", []SampleStruct{
+			{Barcode: "Sample1", TestName: "Test1", ResultText: "NEG", InstrumentID: "Instrument1"},
+		}, ""},
+		{"Too few columns", "Sample1;Test1;;1.00;;\n", nil, "wrong number of fields"},
+		{"Invalid RSLTTYPE_CT", "Sample1;Test1;;1.00;;high;Instrument1\n", nil, "line 1: invalid RSLTTYPE_CT 'high'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{logLvl: CRITICAL}
+
+			samples, err := ParseFlowG(strings.NewReader(c.content))
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFlowG returned error: %v", err)
+			}
+			if !reflect.DeepEqual(samples, c.want) {
+				t.Errorf("Expected samples %+v, got %+v", c.want, samples)
+			}
+		})
+	}
+}
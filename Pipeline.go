@@ -0,0 +1,138 @@
+package FlowG
+
+import (
+	"errors"
+	"time"
+)
+
+// Pipeline is an independent watch pipeline with its own directories, logging and callback, allowing a single
+// process to run integrations for several instruments concurrently. The package-level functions operate on a
+// default pipeline that is configured through SetConfig.
+type Pipeline struct {
+	name   string
+	config *configStruct
+}
+
+// Config holds the configuration of a Pipeline created with New. The fields correspond to the SetConfig keys of the
+// same name, zero values leave the default of a key in place.
+type Config struct {
+	Name         string // Identifies the pipeline, e.g. the instrument it integrates
+	GlimsDir     string
+	ImportDir    string
+	ProcessedDir string
+	ErrorDir     string
+	LogDir       string
+	LogPrefix    string
+	LogLvl       uint8
+
+	ShutdownTimeout     time.Duration
+	StableQuietPeriod   time.Duration
+	StablePolls         int
+	StableMaxWait       time.Duration
+	StableExclusiveOpen bool
+	WatcherBackend      string
+	PollInterval        time.Duration
+	MaxWorkers          int
+	QueueSize           int
+	QueueFullPolicy     string
+	InstrumentFunc      func(string) string
+	SerializeInstrument bool
+	IncludePatterns     []string
+	ExcludePatterns     []string
+	Recursive           bool
+	FlattenSubdirs      bool
+	FileHook            func(FileEvent, error)
+	CallbackTimeout     time.Duration
+	MaxAttempts         int
+	RetryBackoff        time.Duration
+	RetryMaxBackoff     time.Duration
+	LedgerDir           string
+	DuplicatePolicy     string
+
+	ArchiveLayout        string
+	ArchiveCompressAfter time.Duration
+	ArchiveRetention     time.Duration
+
+	NumberFormats      map[string]NumberFormat
+	OutputColumns      []Column
+	AnalysisTimeLayout string
+	TestCatalog        *TestCatalog
+	CatalogPolicy      string
+	TestMapping        *TestMapping
+	BarcodeValidator   *BarcodeValidator
+}
+
+// New creates a Pipeline from cfg, validating every setting like SetConfig does. LogDir is required, as every
+// pipeline logs to its own log files.
+func New(cfg Config) (*Pipeline, error) {
+	if cfg.LogDir == "" {
+		return nil, errors.New("logDir is required")
+	}
+
+	p := &Pipeline{name: cfg.Name, config: &configStruct{}}
+	settings := []struct {
+		key   string
+		value interface{}
+		isSet bool
+	}{
+		{"glimsDir", cfg.GlimsDir, cfg.GlimsDir != ""},
+		{"importDir", cfg.ImportDir, cfg.ImportDir != ""},
+		{"processedDir", cfg.ProcessedDir, cfg.ProcessedDir != ""},
+		{"errorDir", cfg.ErrorDir, cfg.ErrorDir != ""},
+		{"logDir", cfg.LogDir, true},
+		{"logPrefix", cfg.LogPrefix, true},
+		{"logLvl", cfg.LogLvl, true},
+		{"shutdownTimeout", cfg.ShutdownTimeout, cfg.ShutdownTimeout != 0},
+		{"stableQuietPeriod", cfg.StableQuietPeriod, cfg.StableQuietPeriod != 0},
+		{"stablePolls", cfg.StablePolls, cfg.StablePolls != 0},
+		{"stableMaxWait", cfg.StableMaxWait, cfg.StableMaxWait != 0},
+		{"stableExclusiveOpen", cfg.StableExclusiveOpen, true},
+		{"watcherBackend", cfg.WatcherBackend, cfg.WatcherBackend != ""},
+		{"pollInterval", cfg.PollInterval, cfg.PollInterval != 0},
+		{"maxWorkers", cfg.MaxWorkers, true},
+		{"queueSize", cfg.QueueSize, true},
+		{"queueFullPolicy", cfg.QueueFullPolicy, cfg.QueueFullPolicy != ""},
+		{"instrumentFunc", cfg.InstrumentFunc, cfg.InstrumentFunc != nil},
+		{"serializeInstrument", cfg.SerializeInstrument, true},
+		{"includePatterns", cfg.IncludePatterns, cfg.IncludePatterns != nil},
+		{"excludePatterns", cfg.ExcludePatterns, cfg.ExcludePatterns != nil},
+		{"recursive", cfg.Recursive, true},
+		{"flattenSubdirs", cfg.FlattenSubdirs, true},
+		{"fileHook", cfg.FileHook, cfg.FileHook != nil},
+		{"callbackTimeout", cfg.CallbackTimeout, cfg.CallbackTimeout != 0},
+		{"maxAttempts", cfg.MaxAttempts, cfg.MaxAttempts != 0},
+		{"retryBackoff", cfg.RetryBackoff, cfg.RetryBackoff != 0},
+		{"retryMaxBackoff", cfg.RetryMaxBackoff, cfg.RetryMaxBackoff != 0},
+		{"ledgerDir", cfg.LedgerDir, cfg.LedgerDir != ""},
+		{"duplicatePolicy", cfg.DuplicatePolicy, cfg.DuplicatePolicy != ""},
+		{"archiveLayout", cfg.ArchiveLayout, cfg.ArchiveLayout != ""},
+		{"archiveCompressAfter", cfg.ArchiveCompressAfter, cfg.ArchiveCompressAfter != 0},
+		{"archiveRetention", cfg.ArchiveRetention, cfg.ArchiveRetention != 0},
+		{"numberFormats", cfg.NumberFormats, cfg.NumberFormats != nil},
+		{"outputColumns", cfg.OutputColumns, cfg.OutputColumns != nil},
+		{"analysisTimeLayout", cfg.AnalysisTimeLayout, cfg.AnalysisTimeLayout != ""},
+		{"testCatalog", cfg.TestCatalog, cfg.TestCatalog != nil},
+		{"catalogPolicy", cfg.CatalogPolicy, cfg.CatalogPolicy != ""},
+		{"testMapping", cfg.TestMapping, cfg.TestMapping != nil},
+		{"barcodeValidator", cfg.BarcodeValidator, cfg.BarcodeValidator != nil},
+	}
+	for _, setting := range settings {
+		if !setting.isSet {
+			continue
+		}
+		if err := p.SetConfig(setting.key, setting.value); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Name returns the name of the pipeline, or an empty string for the default pipeline.
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+// defaultPipeline returns the pipeline used by the package-level functions, which is configured through SetConfig.
+func defaultPipeline() *Pipeline {
+	return &Pipeline{config: config}
+}
@@ -0,0 +1,121 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	logDir := t.TempDir()
+
+	cases := []struct {
+		name    string
+		cfg     Config
+		wantErr error
+	}{
+		{"Minimal config", Config{LogDir: logDir}, nil},
+		{"Full config", Config{Name: "Analyser1", ImportDir: logDir, LogDir: logDir, LogLvl: ERROR, MaxWorkers: 2, WatcherBackend: BackendPoll, IncludePatterns: []string{"*.csv"}}, nil},
+		{"Missing logDir", Config{ImportDir: logDir}, errors.New("logDir is required")},
+		{"Non-existing directory", Config{LogDir: logDir, ImportDir: "/does/not/exist"}, errors.New("cannot find or access directory: /does/not/exist")},
+		{"Invalid setting", Config{LogDir: logDir, LogLvl: 9}, errors.New("logLvl requires a valid log level, use 0 (DEBUG), 1 (INFO), 2 (WARN), 3 (ERROR), or 4 (CRITICAL)")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := New(c.cfg)
+			if (err != nil && c.wantErr == nil) || (err == nil && c.wantErr != nil) || (err != nil && c.wantErr != nil && err.Error() != c.wantErr.Error()) {
+				t.Fatalf("New() returned error %q, wanted error %q", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if p.Name() != c.cfg.Name {
+				t.Errorf("Expected name %q, got %q", c.cfg.Name, p.Name())
+			}
+			lvl, _ := p.GetConfig("logLvl")
+			if lvl != c.cfg.LogLvl {
+				t.Errorf("Expected logLvl %d, got %v", c.cfg.LogLvl, lvl)
+			}
+		})
+	}
+}
+
+func TestPipelinesConcurrently(t *testing.T) {
+	type pipelineDirs struct {
+		importDir, processedDir, errorDir, logDir string
+	}
+	names := []string{"Analyser1", "Analyser2"}
+	dirs := make(map[string]pipelineDirs)
+	handled := make(chan [2]string, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan error, len(names))
+	for _, name := range names {
+		d := pipelineDirs{t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()}
+		dirs[name] = d
+
+		p, err := New(Config{
+			Name:              name,
+			ImportDir:         d.importDir,
+			ProcessedDir:      d.processedDir,
+			ErrorDir:          d.errorDir,
+			LogDir:            d.logDir,
+			LogPrefix:         name,
+			LogLvl:            INFO,
+			StableQuietPeriod: 100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("New() returned error %v", err)
+		}
+
+		go func(name string) {
+			results <- p.FileWatchContext(ctx, func(path string) bool {
+				handled <- [2]string{name, filepath.Base(path)}
+				return true
+			})
+		}(name)
+	}
+
+	// Allow time for the pipelines to initialize
+	time.Sleep(100 * time.Millisecond)
+	for _, name := range names {
+		err := os.WriteFile(filepath.Join(dirs[name].importDir, name+".txt"), []byte("data"), 0644)
+		if err != nil {
+			t.Fatalf("Error creating test file: %v", err)
+		}
+	}
+
+	for range names {
+		select {
+		case h := <-handled:
+			if h[1] != h[0]+".txt" {
+				t.Errorf("Pipeline %s handled file %s of another pipeline", h[0], h[1])
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for the pipelines to process their files")
+		}
+	}
+
+	cancel()
+	for range names {
+		if err := <-results; err != nil {
+			t.Errorf("FileWatchContext() returned error %v", err)
+		}
+	}
+
+	for _, name := range names {
+		processed, _ := filepath.Glob(filepath.Join(dirs[name].processedDir, "*_"+name+".txt"))
+		if len(processed) != 1 {
+			t.Errorf("Expected the file of %s in its own processedDir", name)
+		}
+		logs, _ := filepath.Glob(filepath.Join(dirs[name].logDir, name+"_*.txt"))
+		if len(logs) != 1 {
+			t.Errorf("Expected a log file with prefix %s in its own logDir", name)
+		}
+	}
+}
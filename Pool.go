@@ -0,0 +1,179 @@
+package FlowG
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Available behaviours for the 'queueFullPolicy' config key
+const (
+	QueueFullBlock = "block" // Stop accepting new files until a queued file has been processed (default)
+	QueueFullSkip  = "skip"  // Leave new files in importDir, and queue them again as soon as a queued file is done
+)
+
+// workerPool runs the file processing jobs of a watch. It limits the number of concurrent jobs to config.maxWorkers
+// with at most config.queueSize jobs waiting, and runs the jobs of a single instrument one at a time in submission
+// order when config.serializeInstrument is set.
+type workerPool struct {
+	running   callbackTracker
+	abandoned callbackTracker // Handlers that keep running after their timeout, not waited for on shutdown
+	workers   chan struct{}   // Nil if the number of workers is unlimited
+	slots     chan struct{}   // Running and waiting jobs, nil if unlimited
+	freed     chan struct{}   // Signalled when a slot becomes free, nil if unlimited
+	serialize bool
+
+	mu    sync.Mutex
+	tails map[string]chan struct{} // Closed when the last submitted job of an instrument is done
+}
+
+func newWorkerPool(cfg *configStruct) *workerPool {
+	p := &workerPool{
+		serialize: cfg.serializeInstrument,
+		tails:     make(map[string]chan struct{}),
+	}
+	if cfg.maxWorkers > 0 {
+		p.workers = make(chan struct{}, cfg.maxWorkers)
+		p.slots = make(chan struct{}, cfg.maxWorkers+cfg.queueSize)
+		p.freed = make(chan struct{}, 1)
+	}
+	return p
+}
+
+// submit queues job for the given instrument. When the queue is full it waits for a free slot if block is set, or
+// returns false otherwise. Also returns false if ctx is cancelled while waiting.
+func (p *workerPool) submit(ctx context.Context, instrument string, job func(), block bool) bool {
+	if p.slots != nil {
+		if block {
+			select {
+			case p.slots <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+		} else {
+			select {
+			case p.slots <- struct{}{}:
+			default:
+				return false
+			}
+		}
+	}
+
+	var previous, done chan struct{}
+	if p.serialize {
+		done = make(chan struct{})
+		p.mu.Lock()
+		previous = p.tails[instrument]
+		p.tails[instrument] = done
+		p.mu.Unlock()
+	}
+
+	p.running.run(func() {
+		if p.slots != nil {
+			defer p.release()
+		}
+		if done != nil {
+			defer p.finish(instrument, done)
+		}
+
+		if previous != nil {
+			<-previous
+		}
+		if p.workers != nil {
+			p.workers <- struct{}{}
+			defer func() { <-p.workers }()
+		}
+		job()
+	})
+	return true
+}
+
+// idle runs wait without holding a worker, so a job waiting for a retry does not keep other jobs from running. The
+// job keeps its place in the queue and in the order of its instrument, and waits for a free worker after wait returns.
+func (p *workerPool) idle(wait func()) {
+	if p.workers == nil {
+		wait()
+		return
+	}
+	<-p.workers
+	defer func() { p.workers <- struct{}{} }()
+	wait()
+}
+
+// release frees the slot of a job that is done, and signals on p.freed that a job can be queued again.
+func (p *workerPool) release() {
+	<-p.slots
+	select {
+	case p.freed <- struct{}{}:
+	default:
+	}
+}
+
+// finish marks the job of instrument that closes done as completed, releasing the next job of that instrument.
+func (p *workerPool) finish(instrument string, done chan struct{}) {
+	close(done)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tails[instrument] == done {
+		delete(p.tails, instrument)
+	}
+}
+
+// wait blocks until all submitted jobs are done, see callbackTracker.wait.
+func (p *workerPool) wait(timeout time.Duration) error {
+	return p.running.wait(timeout)
+}
+
+// callbackTracker keeps track of the callback goroutines started by FileWatchContext, so they can be drained on
+// shutdown.
+type callbackTracker struct {
+	wg    sync.WaitGroup
+	count atomic.Int64
+}
+
+// run executes fn in a new goroutine that is tracked until it returns. A nil tracker runs fn untracked.
+func (t *callbackTracker) run(fn func()) {
+	if t == nil {
+		go fn()
+		return
+	}
+	t.wg.Add(1)
+	t.count.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.count.Add(-1)
+		fn()
+	}()
+}
+
+// active returns the number of tracked goroutines that are running. A nil tracker has none.
+func (t *callbackTracker) active() int64 {
+	if t == nil {
+		return 0
+	}
+	return t.count.Load()
+}
+
+// wait blocks until all tracked goroutines have returned, or until timeout has passed. A zero timeout waits
+// indefinitely.
+func (t *callbackTracker) wait(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	if timeout == 0 {
+		<-done
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%w: %d callback(s) still running", ErrShutdownTimeout, t.count.Load())
+	}
+}
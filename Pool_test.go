@@ -0,0 +1,178 @@
+package FlowG
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	cases := []struct {
+		name          string
+		maxWorkers    int
+		queueSize     int
+		serialize     bool
+		block         bool
+		jobs          int
+		wantQueued    int
+		wantMaxActive int64
+	}{
+		{"Unlimited workers", 0, 0, false, false, 10, 10, 10},
+		{"Limited workers, blocking queue", 2, 0, false, true, 10, 10, 2},
+		{"Limited workers, full queue skips", 2, 3, false, false, 10, 5, 2},
+		{"Serialized instrument", 4, 10, true, true, 10, 10, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				maxWorkers:          c.maxWorkers,
+				queueSize:           c.queueSize,
+				serializeInstrument: c.serialize,
+			}
+			pool := newWorkerPool(config)
+
+			var active, maxActive atomic.Int64
+			var mu sync.Mutex
+			var order []int
+			release := make(chan struct{})
+
+			queued := 0
+			for i := 0; i < c.jobs; i++ {
+				i := i
+				job := func() {
+					n := active.Add(1)
+					for {
+						m := maxActive.Load()
+						if n <= m || maxActive.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					mu.Lock()
+					order = append(order, i)
+					mu.Unlock()
+					if !c.block {
+						<-release
+					} else {
+						time.Sleep(10 * time.Millisecond)
+					}
+					active.Add(-1)
+				}
+				if pool.submit(context.Background(), "instrument", job, c.block) {
+					queued++
+				}
+			}
+			// Allow the unblocked jobs to start before releasing them
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+
+			err := pool.wait(5 * time.Second)
+			if err != nil {
+				t.Fatalf("wait() returned error %v", err)
+			}
+			if queued != c.wantQueued {
+				t.Errorf("Expected %d queued jobs, got %d", c.wantQueued, queued)
+			}
+			if maxActive.Load() != c.wantMaxActive {
+				t.Errorf("Expected at most %d concurrent jobs, got %d", c.wantMaxActive, maxActive.Load())
+			}
+			if c.serialize {
+				wantOrder := make([]int, c.jobs)
+				for i := range wantOrder {
+					wantOrder[i] = i
+				}
+				if !reflect.DeepEqual(order, wantOrder) {
+					t.Errorf("Expected jobs in submission order, got %v", order)
+				}
+			}
+		})
+	}
+}
+
+func TestFileWatchQueueFullSkip(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       CRITICAL,
+
+		stableQuietPeriod: 100 * time.Millisecond,
+		maxWorkers:        1,
+		queueFullPolicy:   QueueFullSkip,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	started := make(chan string, 5)
+	release := make(chan struct{})
+	callback := func(path string) bool {
+		started <- filepath.Base(path)
+		<-release
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- FileWatchContext(ctx, callback)
+	}()
+
+	// Allow time for FileWatchContext to initialize
+	time.Sleep(100 * time.Millisecond)
+	names := []string{"first.txt", "second.txt", "third.txt"}
+	if err = os.WriteFile(filepath.Join(config.importDir, names[0]), []byte("data"), 0644); err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for the first file")
+	}
+
+	// The only slot is taken, so these files are skipped, and queued again once the first file is done
+	for _, name := range names[1:] {
+		if err = os.WriteFile(filepath.Join(config.importDir, name), []byte("data"), 0644); err != nil {
+			t.Fatalf("Error creating test file: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+
+	order := []string{names[0]}
+	for len(order) < len(names) {
+		select {
+		case name := <-started:
+			order = append(order, name)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timeout waiting for the skipped files, got %v", order)
+		}
+	}
+	if !reflect.DeepEqual(order, names) {
+		t.Errorf("Expected the skipped files in order of arrival %v, got %v", names, order)
+	}
+
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchContext() returned error %v", err)
+	}
+	if remaining, _ := os.ReadDir(config.importDir); len(remaining) != 0 {
+		t.Errorf("Expected importDir to be empty, got %d file(s)", len(remaining))
+	}
+}
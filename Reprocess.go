@@ -0,0 +1,309 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reprocessDir is the directory within errorDir in which files are staged while they are being reprocessed.
+const reprocessDir = ".reprocess"
+
+// ReprocessFilter selects the files in errorDir to reprocess. A zero ReprocessFilter selects all files.
+type ReprocessFilter struct {
+	// Pattern is matched against the original file name, without the timestamp prefix. It is a glob
+	// (e.g. '*.csv'), or a regular expression when prefixed with 're:'. An empty pattern matches all files.
+	Pattern string
+	// From and To limit the selection to files that were moved to errorDir within this period. A zero value leaves
+	// that side of the period open.
+	From time.Time
+	To   time.Time
+}
+
+// ReprocessResult describes the outcome of reprocessing a single file from errorDir.
+type ReprocessResult struct {
+	File string // File in errorDir that was reprocessed
+	Name string // Original name of the file, without the timestamp prefix
+	Dest string // Destination in processedDir if reprocessing succeeded
+	Err  error  // Error returned by the handler, nil if reprocessing succeeded
+}
+
+// errorFile is a file in errorDir selected for reprocessing.
+type errorFile struct {
+	path   string
+	subDir string
+	name   string
+	failed time.Time
+}
+
+// Reprocess reprocesses files in the errorDir of the default pipeline, see Pipeline.Reprocess.
+func Reprocess(ctx context.Context, filter ReprocessFilter, handler EventHandler) ([]ReprocessResult, error) {
+	return defaultPipeline().Reprocess(ctx, filter, handler)
+}
+
+// Reprocess hands the files in errorDir selected by filter to handler again, oldest first. The file is presented
+// under its original name, without the timestamp prefix added by FileMove, and failures are retried as configured
+// with the 'maxAttempts' config key. Files that are now processed successfully are moved to processedDir, and the
+// file and its error sidecar are removed from errorDir. Files that fail again are left in errorDir with an updated
+// sidecar, so the original is never lost. Returns the outcome per file, and an error if errorDir could not be read
+// or ctx was cancelled before all files were reprocessed.
+func (p *Pipeline) Reprocess(ctx context.Context, filter ReprocessFilter, handler EventHandler) ([]ReprocessResult, error) {
+	files, err := p.selectErrorFiles(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ReprocessResult, 0, len(files))
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		result, stopped := p.reprocessFile(ctx, file, handler)
+		if stopped {
+			return results, ctx.Err()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// selectErrorFiles returns the files in errorDir matching filter, sorted by the time they were moved to errorDir.
+func (p *Pipeline) selectErrorFiles(filter ReprocessFilter) ([]errorFile, error) {
+	var pattern *filePattern
+	if filter.Pattern != "" {
+		compiled, err := compilePattern(filter.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = &compiled
+	}
+
+	var files []errorFile
+	err := filepath.WalkDir(p.config.errorDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == reprocessDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), errorSidecarSuffix) {
+			return nil
+		}
+
+		file, err := p.parseErrorFile(path, d)
+		if err != nil {
+			return err
+		}
+		if pattern != nil && !pattern.match(file.name) {
+			return nil
+		}
+		if (!filter.From.IsZero() && file.failed.Before(filter.From)) ||
+			(!filter.To.IsZero() && file.failed.After(filter.To)) {
+			return nil
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read errorDir: %w", err)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].failed.Before(files[j].failed)
+	})
+	return files, nil
+}
+
+// parseErrorFile recovers the original name of a file in errorDir and the time it was moved there from the
+// timestamp prefix added by FileMove. Files without a prefix keep their name and use their modification time.
+func (p *Pipeline) parseErrorFile(path string, d fs.DirEntry) (errorFile, error) {
+	rel, err := filepath.Rel(p.config.errorDir, path)
+	if err != nil {
+		return errorFile{}, err
+	}
+	file := errorFile{path: path, subDir: filepath.Dir(rel), name: d.Name()}
+
+	if name, failed, ok := parseArchivedName(d.Name()); ok {
+		file.name = name
+		file.failed = failed
+		return file, nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return errorFile{}, err
+	}
+	file.failed = info.ModTime()
+	return file, nil
+}
+
+// reprocessFile runs handler for a copy of file under its original name. Returns true if ctx was cancelled while
+// waiting for a retry, in which case file is left untouched.
+func (p *Pipeline) reprocessFile(ctx context.Context, file errorFile, handler EventHandler) (ReprocessResult, bool) {
+	result := ReprocessResult{File: file.path, Name: file.name}
+
+	stagingDir := filepath.Join(p.config.errorDir, reprocessDir, file.subDir)
+	stagingPath := filepath.Join(stagingDir, file.name)
+	if err := os.MkdirAll(stagingDir, os.ModePerm); err != nil {
+		result.Err = fmt.Errorf("cannot create staging directory: %w", err)
+		p.Logging(fmt.Sprintf("Cannot reprocess '%s': %v", file.path, result.Err), ERROR)
+		return result, false
+	}
+	// A staged copy can be left behind by an interrupted run
+	_ = os.Remove(stagingPath)
+	if err := copySynced(file.path, stagingPath); err != nil {
+		result.Err = fmt.Errorf("cannot stage file: %w", err)
+		p.Logging(fmt.Sprintf("Cannot reprocess '%s': %v", file.path, result.Err), ERROR)
+		return result, false
+	}
+
+	event := FileEvent{
+		Path:     stagingPath,
+		RelPath:  filepath.Join(file.subDir, file.name),
+		Detected: time.Now(),
+		Attempt:  1,
+		Pipeline: p.name,
+		ctx:      ctx,
+	}
+	if p.config.instrumentFunc != nil {
+		event.Instrument = p.config.instrumentFunc(stagingPath)
+	}
+	if info, err := os.Stat(stagingPath); err == nil {
+		event.Size = info.Size()
+	}
+
+	stopped, err := p.runWithRetry(&event, handler)
+	if stopped {
+		_ = os.Remove(stagingPath)
+		p.Logging(fmt.Sprintf("Reprocessing stopped before '%s' was retried, leaving it in errorDir", file.path), INFO)
+		return result, true
+	}
+	result.Err = err
+
+	if err != nil {
+		if removeErr := os.Remove(stagingPath); removeErr != nil {
+			p.Logging(fmt.Sprintf("Cannot remove staged copy '%s': %v", stagingPath, removeErr), WARNING)
+		}
+		if !errors.Is(err, ErrCallbackFailed) {
+			p.writeErrorSidecar(file.path, event, err)
+		}
+		p.Logging(fmt.Sprintf("Reprocessing '%s' failed again, leaving it in errorDir", file.path), WARNING)
+	} else if dest, moveErr := p.archiveReprocessed(stagingPath, file.subDir); moveErr != nil {
+		// The handler succeeded, but the original is kept in errorDir as it could not be replaced by the copy
+		result.Err = fmt.Errorf("cannot move reprocessed file to processedDir: %w", moveErr)
+		_ = os.Remove(stagingPath)
+		p.Logging(fmt.Sprintf("Error while moving file: %v", moveErr), ERROR)
+	} else {
+		result.Dest = dest
+		for _, path := range []string{file.path, file.path + errorSidecarSuffix} {
+			if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				p.Logging(fmt.Sprintf("Cannot remove '%s' from errorDir: %v", path, removeErr), WARNING)
+			}
+		}
+		p.Logging(fmt.Sprintf("Reprocessed '%s' successfully, moved to '%s'", file.path, dest), INFO)
+	}
+
+	if p.config.fileHook != nil {
+		p.config.fileHook(event, result.Err)
+	}
+	return result, false
+}
+
+// archiveReprocessed records a successfully reprocessed file in the ledger and moves it to processedDir.
+func (p *Pipeline) archiveReprocessed(path string, subDir string) (string, error) {
+	if p.duplicatePolicy() != "" {
+		if hash, err := hashFile(path); err == nil {
+			p.recordFile(path, hash)
+		}
+	}
+	return p.archiveFile(path, subDir, true)
+}
+
+// ReprocessCommand runs the reprocess subcommand for the default pipeline, see Pipeline.ReprocessCommand.
+func ReprocessCommand(ctx context.Context, args []string, handler EventHandler, out io.Writer) error {
+	return defaultPipeline().ReprocessCommand(ctx, args, handler, out)
+}
+
+// ReprocessCommand implements a 'reprocess' subcommand for an instrument program, selecting files with the command
+// line flags in args (without the subcommand itself) and reprocessing them with handler. The outcome per file and a
+// summary are written to out. Available flags:
+//
+//	-pattern  glob, or regular expression prefixed with 're:', matched against the original file name
+//	-from     only files moved to errorDir on or after this date (2006-01-02) or time (RFC 3339)
+//	-to       only files moved to errorDir on or before this date (2006-01-02) or time (RFC 3339)
+//	-all      reprocess all files in errorDir
+//
+// At least one of the flags is required, to prevent reprocessing all files by accident. Returns an error if the
+// flags are invalid or any of the files failed again.
+func (p *Pipeline) ReprocessCommand(ctx context.Context, args []string, handler EventHandler, out io.Writer) error {
+	flags := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	flags.SetOutput(out)
+	pattern := flags.String("pattern", "", "glob, or regular expression prefixed with 're:', matched against the original file name")
+	from := flags.String("from", "", "only files moved to errorDir on or after this date (2006-01-02) or time (RFC 3339)")
+	to := flags.String("to", "", "only files moved to errorDir on or before this date (2006-01-02) or time (RFC 3339)")
+	all := flags.Bool("all", false, "reprocess all files in errorDir")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if !*all && *pattern == "" && *from == "" && *to == "" {
+		return errors.New("select files with -pattern, -from or -to, or use -all to reprocess all files")
+	}
+
+	filter := ReprocessFilter{Pattern: *pattern}
+	var err error
+	if filter.From, err = parseReprocessTime(*from, false); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if filter.To, err = parseReprocessTime(*to, true); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	results, err := p.Reprocess(ctx, filter, handler)
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			_, _ = fmt.Fprintf(out, "FAILED %s: %v\n", result.File, result.Err)
+		} else {
+			_, _ = fmt.Fprintf(out, "OK     %s -> %s\n", result.File, result.Dest)
+		}
+	}
+	_, _ = fmt.Fprintf(out, "Reprocessed %d files: %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed again", failed, len(results))
+	}
+	return nil
+}
+
+// parseReprocessTime parses a date (2006-01-02) or RFC 3339 time. A date marks the start of that day, or its end if
+// endOfDay is set. An empty value returns the zero time.
+func parseReprocessTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		if endOfDay {
+			return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+		}
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,179 @@
+package FlowG
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReprocess(t *testing.T) {
+	cases := []struct {
+		name       string
+		filter     ReprocessFilter
+		handlerErr error
+		wantNames  []string
+		wantLeft   int
+	}{
+		{"All files succeed", ReprocessFilter{}, nil, []string{"old.csv", "new.csv", "plain.txt"}, 0},
+		{"Glob on original name", ReprocessFilter{Pattern: "*.csv"}, nil, []string{"old.csv", "new.csv"}, 1},
+		{"Regexp on original name", ReprocessFilter{Pattern: `re:^new\.`}, nil, []string{"new.csv"}, 2},
+		{"Date range", ReprocessFilter{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+			To: time.Date(2024, 12, 31, 0, 0, 0, 0, time.Local)}, nil, []string{"new.csv"}, 2},
+		{"Files fail again", ReprocessFilter{Pattern: "*.csv"}, errors.New("still broken"), []string{"old.csv", "new.csv"}, 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       CRITICAL,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			files := map[string]string{
+				"20230105101500123_old.csv": "old",
+				"20240610080000000_new.csv": "new",
+				"plain.txt":                 "plain",
+			}
+			for name, content := range files {
+				if err = os.WriteFile(filepath.Join(config.errorDir, name), []byte(content), 0644); err != nil {
+					t.Fatalf("Error creating test file: %v", err)
+				}
+			}
+			if err = os.WriteFile(filepath.Join(config.errorDir, "20240610080000000_new.csv.error.txt"), []byte("Error: parser bug\n"), 0644); err != nil {
+				t.Fatalf("Error creating sidecar: %v", err)
+			}
+
+			var handled []string
+			handler := func(event FileEvent) error {
+				content, err := os.ReadFile(event.Path)
+				if err != nil || !strings.Contains(event.Path, reprocessDir) || len(content) == 0 {
+					t.Errorf("Handler got unexpected file %s (%q, %v)", event.Path, content, err)
+				}
+				handled = append(handled, event.RelPath)
+				return c.handlerErr
+			}
+
+			results, err := Reprocess(context.Background(), c.filter, handler)
+			if err != nil {
+				t.Fatalf("Reprocess returned error: %v", err)
+			}
+			if strings.Join(handled, ",") != strings.Join(c.wantNames, ",") {
+				t.Errorf("Expected handled files %v, got %v", c.wantNames, handled)
+			}
+			if len(results) != len(c.wantNames) {
+				t.Fatalf("Expected %d results, got %d", len(c.wantNames), len(results))
+			}
+			for _, result := range results {
+				if !errors.Is(result.Err, c.handlerErr) || (c.handlerErr == nil) != (result.Dest != "") {
+					t.Errorf("Unexpected result %+v", result)
+				}
+			}
+
+			left, _ := filepath.Glob(filepath.Join(config.errorDir, "*"))
+			originals := 0
+			for _, path := range left {
+				if info, err := os.Stat(path); err == nil && !info.IsDir() && !strings.HasSuffix(path, errorSidecarSuffix) {
+					originals++
+				}
+			}
+			if originals != c.wantLeft {
+				t.Errorf("Expected %d files left in errorDir, got %d (%v)", c.wantLeft, originals, left)
+			}
+			staged, _ := filepath.Glob(filepath.Join(config.errorDir, reprocessDir, "*"))
+			if len(staged) != 0 {
+				t.Errorf("Expected no staged copies, got %v", staged)
+			}
+
+			if c.handlerErr != nil {
+				sidecar, err := os.ReadFile(filepath.Join(config.errorDir, "20230105101500123_old.csv.error.txt"))
+				if err != nil || !strings.Contains(string(sidecar), "still broken") {
+					t.Errorf("Expected updated sidecar, got %q (%v)", sidecar, err)
+				}
+			} else {
+				processed, _ := filepath.Glob(filepath.Join(config.processedDir, "*"))
+				if len(processed) != len(c.wantNames) {
+					t.Errorf("Expected %d files in processedDir, got %v", len(c.wantNames), processed)
+				}
+			}
+		})
+	}
+}
+
+func TestReprocessCommand(t *testing.T) {
+	cases := []struct {
+		name    string
+		args    []string
+		wantErr string
+		wantOut string
+	}{
+		{"No selection", nil, "select files with", ""},
+		{"Invalid date", []string{"-from", "yesterday"}, "invalid -from", ""},
+		{"Unexpected argument", []string{"-all", "extra"}, "unexpected arguments", ""},
+		{"All files", []string{"-all"}, "", "Reprocessed 1 files: 1 succeeded, 0 failed"},
+		{"Date range excluding file", []string{"-to", "2020-01-01"}, "", "Reprocessed 0 files"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       CRITICAL,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+			if err = os.WriteFile(filepath.Join(config.errorDir, "20240610080000000_run.csv"), []byte("data"), 0644); err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			var out bytes.Buffer
+			err = ReprocessCommand(context.Background(), c.args, BoolHandler(func(string) bool { return true }), &out)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReprocessCommand returned error: %v", err)
+			}
+			if !strings.Contains(out.String(), c.wantOut) {
+				t.Errorf("Expected output containing %q, got %q", c.wantOut, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package FlowG
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Comparator qualifies a numeric result that lies outside the measuring range of an assay, such as '>1000'.
+type Comparator string
+
+// Available comparators for SampleStruct.ResultComparator
+const (
+	LessThan       Comparator = "<"
+	LessOrEqual    Comparator = "<="
+	GreaterThan    Comparator = ">"
+	GreaterOrEqual Comparator = ">="
+)
+
+// noComparator marks an exact numeric result.
+const noComparator Comparator = ""
+
+// comparatorChars are the characters a comparator consists of.
+const comparatorChars = "<>="
+
+// valid reports whether c is one of the available comparators or empty.
+func (c Comparator) valid() bool {
+	switch c {
+	case noComparator, LessThan, LessOrEqual, GreaterThan, GreaterOrEqual:
+		return true
+	}
+	return false
+}
+
+// SetResult sets the result of the sample from the text an instrument reports. A number, optionally preceded by a
+// comparator such as '>1000' or '<0.5', is stored in Result and ResultComparator. Any other text, such as 'POS' or
+// 'INVALID', is stored in ResultText.
+func (s *SampleStruct) SetResult(raw string) {
+	s.Result, s.ResultComparator, s.ResultText = nil, noComparator, ""
+
+	raw = strings.TrimSpace(raw)
+	number := strings.TrimLeft(raw, comparatorChars)
+	comparator := Comparator(raw[:len(raw)-len(number)])
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err == nil && comparator.valid() && !math.IsNaN(value) && !math.IsInf(value, 0) {
+		s.Result = &value
+		s.ResultComparator = comparator
+		return
+	}
+	s.ResultText = raw
+}
+
+// normalizeResult returns sample with a ResultText that holds a number, such as '1000' or '>1000', moved into Result
+// and ResultComparator like SetResult does, and with the whitespace around other text results removed. ParseFlowG
+// reads such text back as a numeric result, so it is written as one.
+func normalizeResult(sample SampleStruct) SampleStruct {
+	if sample.Result == nil && sample.ResultComparator == noComparator && sample.ResultText != "" {
+		sample.SetResult(sample.ResultText)
+	}
+	return sample
+}
+
+// resultProblem returns why the result fields of sample cannot be written, or an empty string if they can. Numbers
+// that cannot be written and read back, NaN and infinity, are rejected.
+func resultProblem(sample SampleStruct) string {
+	for _, result := range []struct {
+		name  string
+		value *float64
+	}{{"Result", sample.Result}, {"ResultINT", sample.ResultINT}, {"ResultCT", sample.ResultCT}} {
+		if result.value != nil && (math.IsNaN(*result.value) || math.IsInf(*result.value, 0)) {
+			return fmt.Sprintf("%s %v is not a finite number", result.name, *result.value)
+		}
+	}
+
+	switch {
+	case sample.Result != nil && sample.ResultText != "":
+		return "both Result and ResultText are set"
+	case !sample.ResultComparator.valid():
+		return fmt.Sprintf("invalid ResultComparator '%s'", sample.ResultComparator)
+	case sample.ResultComparator != noComparator && sample.Result == nil:
+		return "ResultComparator is set without a numeric Result"
+	case strings.ContainsAny(sample.ResultText, ";\r\n\""):
+		return fmt.Sprintf("ResultText '%s' contains a separator, quote or line break", sample.ResultText)
+	}
+	return ""
+}
+
+// resultColumn returns the RESULT column of sample: the formatted numeric result with its comparator, or the text
+// result.
+func (p *Pipeline) resultColumn(sample SampleStruct) string {
+	if sample.Result == nil {
+		return sample.ResultText
+	}
+	return string(sample.ResultComparator) + p.formatResult(sample.TestName, sample.Result)
+}
@@ -0,0 +1,80 @@
+package FlowG
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSetResult(t *testing.T) {
+	cases := []struct {
+		name           string
+		raw            string
+		wantResult     *float64
+		wantComparator Comparator
+		wantText       string
+	}{
+		{"Number", "27.5", ptrFloat64(27.5), noComparator, ""},
+		{"Greater than", ">1000", ptrFloat64(1000), GreaterThan, ""},
+		{"Less than with space", " < 0.5 ", ptrFloat64(0.5), LessThan, ""},
+		{"Greater or equal", ">=40", ptrFloat64(40), GreaterOrEqual, ""},
+		{"Positive", "POS", nil, noComparator, "POS"},
+		{"Not a number", "NaN", nil, noComparator, "NaN"},
+		{"Invalid comparator", "=>5", nil, noComparator, "=>5"},
+		{"Empty", "", nil, noComparator, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sample := SampleStruct{ResultText: "previous"}
+			sample.SetResult(c.raw)
+			if (sample.Result == nil) != (c.wantResult == nil) || (sample.Result != nil && *sample.Result != *c.wantResult) {
+				t.Errorf("SetResult(%q): expected Result %v, got %v", c.raw, c.wantResult, sample.Result)
+			}
+			if sample.ResultComparator != c.wantComparator || sample.ResultText != c.wantText {
+				t.Errorf("SetResult(%q): expected %q and text %q, got %q and %q", c.raw, c.wantComparator, c.wantText,
+					sample.ResultComparator, sample.ResultText)
+			}
+		})
+	}
+}
+
+func TestResultColumn(t *testing.T) {
+	cases := []struct {
+		name        string
+		sample      SampleStruct
+		wantColumn  string
+		wantProblem string
+	}{
+		{"Numeric", SampleStruct{Result: ptrFloat64(27.456)}, "27.46", ""},
+		{"Qualified", SampleStruct{Result: ptrFloat64(1000), ResultComparator: GreaterThan}, ">1000.00", ""},
+		{"Text", SampleStruct{ResultText: "NEG"}, "NEG", ""},
+		{"Empty", SampleStruct{}, "", ""},
+		{"Numeric and text", SampleStruct{Result: ptrFloat64(1), ResultText: "POS"}, "", "both Result and ResultText are set"},
+		{"Comparator without value", SampleStruct{ResultComparator: LessThan}, "", "ResultComparator is set without a numeric Result"},
+		{"Unknown comparator", SampleStruct{Result: ptrFloat64(1), ResultComparator: "~"}, "", "invalid ResultComparator '~'"},
+		{"Text with separator", SampleStruct{ResultText: "POS;NEG"}, "", "ResultText 'POS;NEG' contains a separator, quote or line break"},
+		{"Numeric text", SampleStruct{ResultText: "1000"}, "1000.00", ""},
+		{"Qualified numeric text", SampleStruct{ResultText: ">5"}, ">5.00", ""},
+		{"Text with whitespace", SampleStruct{ResultText: " POS"}, "POS", ""},
+		{"Text that is not a comparator", SampleStruct{ResultText: "<<5"}, "<<5", ""},
+		{"NaN", SampleStruct{Result: ptrFloat64(math.NaN())}, "", "Result NaN is not a finite number"},
+		{"Infinite CT", SampleStruct{ResultCT: ptrFloat64(math.Inf(1))}, "", "ResultCT +Inf is not a finite number"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{}
+			sample := normalizeResult(c.sample)
+			problem := resultProblem(sample)
+			if problem != c.wantProblem {
+				t.Errorf("Expected problem %q, got %q", c.wantProblem, problem)
+			}
+			if problem != "" {
+				return
+			}
+			if column := defaultPipeline().resultColumn(sample); column != c.wantColumn {
+				t.Errorf("Expected RESULT column %q, got %q", c.wantColumn, column)
+			}
+		})
+	}
+}
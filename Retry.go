@@ -0,0 +1,60 @@
+package FlowG
+
+import (
+	"errors"
+	"time"
+)
+
+// Defaults used for retrying failed files when the corresponding config key is not set
+const (
+	defaultRetryBackoff    = 5 * time.Second
+	defaultRetryMaxBackoff = 5 * time.Minute
+)
+
+// retryableError marks an error as transient, see Retryable.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable marks err as a transient failure, such as an unavailable network share or a file that is still locked
+// by the instrument. When an EventHandler returns a retryable error, the file is processed again after a backoff
+// period, up to the number of attempts set by the 'maxAttempts' config key. All other errors are permanent and move
+// the file to errorDir immediately. Returns nil if err is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err: err}
+}
+
+// IsRetryable reports whether err, or any error it wraps, was marked as transient with Retryable.
+func IsRetryable(err error) bool {
+	var retryable retryableError
+	return errors.As(err, &retryable)
+}
+
+// retryDelay returns the backoff before the attempt following the given attempt. The delay starts at
+// config.retryBackoff and doubles for every attempt, up to config.retryMaxBackoff.
+func (p *Pipeline) retryDelay(attempt int) time.Duration {
+	delay := p.config.retryBackoff
+	if delay == 0 {
+		delay = defaultRetryBackoff
+	}
+	maxDelay := p.config.retryMaxBackoff
+	if maxDelay == 0 {
+		maxDelay = defaultRetryMaxBackoff
+	}
+
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,224 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	transient := errors.New("share unavailable")
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Nil error", nil, false},
+		{"Permanent error", transient, false},
+		{"Retryable error", Retryable(transient), true},
+		{"Wrapped retryable error", fmt.Errorf("writing output: %w", Retryable(transient)), true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := IsRetryable(c.err); actual != c.expected {
+				t.Errorf("IsRetryable(%v): expected %v, got %v", c.err, c.expected, actual)
+			}
+		})
+	}
+
+	if Retryable(nil) != nil {
+		t.Errorf("Retryable(nil): expected nil")
+	}
+	if !errors.Is(Retryable(transient), transient) {
+		t.Errorf("Retryable() should wrap the original error")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	config = &configStruct{
+		retryBackoff:    time.Second,
+		retryMaxBackoff: 5 * time.Second,
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if actual := defaultPipeline().retryDelay(i + 1); actual != want {
+			t.Errorf("retryDelay(%d): expected %s, got %s", i+1, want, actual)
+		}
+	}
+}
+
+func TestFileWatchRetry(t *testing.T) {
+	cases := []struct {
+		name         string
+		failAttempts int
+		retryable    bool
+		wantAttempts int
+		wantDir      string
+	}{
+		{"Transient failure recovers", 1, true, 2, "./processed"},
+		{"Transient failure persists", 10, true, 3, "./error"},
+		{"Permanent failure", 10, false, 1, "./error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config = &configStruct{
+				glimsDir:     "./glims",
+				importDir:    "./import",
+				processedDir: "./processed",
+				errorDir:     "./error",
+				logDir:       "./log",
+				logPrefix:    "Test",
+				logLvl:       WARNING,
+
+				stableQuietPeriod: 100 * time.Millisecond,
+				maxAttempts:       3,
+				retryBackoff:      50 * time.Millisecond,
+			}
+
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			done := make(chan FileEvent, 1)
+			config.fileHook = func(event FileEvent, err error) {
+				done <- event
+			}
+			handler := func(event FileEvent) error {
+				if event.Attempt <= c.failAttempts {
+					if c.retryable {
+						return Retryable(errors.New("GLIMS share unavailable"))
+					}
+					return errors.New("unknown plate layout")
+				}
+				return nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchEvents(ctx, handler)
+			}()
+
+			// Allow time for FileWatchEvents to initialize
+			time.Sleep(100 * time.Millisecond)
+			err = os.WriteFile(filepath.Join(config.importDir, "testFile.txt"), []byte("data"), 0644)
+			if err != nil {
+				t.Fatalf("Error creating test file: %v", err)
+			}
+
+			select {
+			case event := <-done:
+				if event.Attempt != c.wantAttempts {
+					t.Errorf("Expected %d attempt(s), got %d", c.wantAttempts, event.Attempt)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatalf("Timeout waiting for the file to be processed")
+			}
+
+			cancel()
+			if err = <-result; err != nil {
+				t.Errorf("FileWatchEvents() returned error %v", err)
+			}
+
+			movedFiles, _ := filepath.Glob(filepath.Join(c.wantDir, "*_testFile.txt"))
+			if len(movedFiles) != 1 {
+				t.Errorf("Expected the file in '%s'", c.wantDir)
+			}
+		})
+	}
+}
+
+func TestFileWatchRetryReleasesWorker(t *testing.T) {
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       CRITICAL,
+
+		stableQuietPeriod: 100 * time.Millisecond,
+		maxWorkers:        1,
+		queueSize:         5,
+		maxAttempts:       2,
+		retryBackoff:      time.Second,
+	}
+
+	err := createTestFolders()
+	defer func() {
+		err = destroyTestFolders()
+		if err != nil {
+			t.Fatalf("Error cleaning up test folders: %v", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Error creating test folders: %v", err)
+	}
+
+	done := make(chan string, 2)
+	config.fileHook = func(event FileEvent, err error) {
+		done <- filepath.Base(event.Path)
+	}
+	firstAttempt := make(chan struct{}, 1)
+	handler := func(event FileEvent) error {
+		if filepath.Base(event.Path) == "retried.txt" && event.Attempt == 1 {
+			firstAttempt <- struct{}{}
+			return Retryable(errors.New("GLIMS share unavailable"))
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- FileWatchEvents(ctx, handler)
+	}()
+
+	// Allow time for FileWatchEvents to initialize
+	time.Sleep(100 * time.Millisecond)
+	if err = os.WriteFile(filepath.Join(config.importDir, "retried.txt"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	select {
+	case <-firstAttempt:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for the first attempt")
+	}
+	if err = os.WriteFile(filepath.Join(config.importDir, "other.txt"), []byte("data"), 0644); err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+
+	// The only worker is free while the first file waits for its retry, so the second file is processed first
+	var order []string
+	for len(order) < 2 {
+		select {
+		case name := <-done:
+			order = append(order, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout waiting for the files to be processed, got %v", order)
+		}
+	}
+	if order[0] != "other.txt" || order[1] != "retried.txt" {
+		t.Errorf("Expected other.txt to be processed during the backoff of retried.txt, got %v", order)
+	}
+
+	cancel()
+	if err = <-result; err != nil {
+		t.Errorf("FileWatchEvents() returned error %v", err)
+	}
+}
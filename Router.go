@@ -0,0 +1,85 @@
+package FlowG
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnmatchedPolicy determines what a Router does with files that match none of its routes.
+type UnmatchedPolicy uint8
+
+// Available policies for files that match none of the routes of a Router
+const (
+	UnmatchedIgnore UnmatchedPolicy = iota // Leave the file in importDir, only logged at DEBUG level
+	UnmatchedLeave                         // Leave the file in importDir and log a WARNING, so it gets noticed
+	UnmatchedError                         // Move the file to errorDir
+)
+
+// Router dispatches files to different callbacks based on their file name. Routes are checked in the order in which
+// they were added, the first matching route handles the file.
+type Router struct {
+	routes    []route
+	unmatched UnmatchedPolicy
+}
+
+// route connects a filename pattern to the handler that processes the matching files.
+type route struct {
+	pattern *filePattern // Nil matches all files
+	handler EventHandler
+}
+
+// NewRouter returns an empty Router that handles files matching none of its routes according to unmatched.
+func NewRouter(unmatched UnmatchedPolicy) *Router {
+	return &Router{unmatched: unmatched}
+}
+
+// Handle registers the callback for files whose name matches pattern. The pattern is a glob (e.g. '*.csv'), or a
+// regular expression when prefixed with 're:' (e.g. 're:^run_\d+\.xml$').
+func (r *Router) Handle(pattern string, callback func(string) bool) error {
+	if callback == nil {
+		return fmt.Errorf("no callback given for pattern '%s'", pattern)
+	}
+	return r.HandleEvent(pattern, BoolHandler(callback))
+}
+
+// HandleEvent registers an EventHandler for files whose name matches pattern, see Handle.
+func (r *Router) HandleEvent(pattern string, handler EventHandler) error {
+	if handler == nil {
+		return fmt.Errorf("no handler given for pattern '%s'", pattern)
+	}
+	p, err := compilePattern(pattern)
+	if err != nil {
+		return err
+	}
+	r.routes = append(r.routes, route{pattern: &p, handler: handler})
+	return nil
+}
+
+// match returns the handler of the first route matching path.
+func (r *Router) match(path string) (EventHandler, bool) {
+	for _, rt := range r.routes {
+		if rt.pattern == nil || rt.pattern.match(path) {
+			return rt.handler, true
+		}
+	}
+	return nil, false
+}
+
+// FileWatchRouter watches the importDir of the default pipeline, see Pipeline.FileWatchRouter.
+func FileWatchRouter(ctx context.Context, router *Router) error {
+	return defaultPipeline().FileWatchRouter(ctx, router)
+}
+
+// FileWatchRouter behaves like FileWatchContext, but hands each file to the callback of the first matching route of
+// router. Files matching none of the routes are handled according to the UnmatchedPolicy of the router.
+func (p *Pipeline) FileWatchRouter(ctx context.Context, router *Router) error {
+	if router == nil || len(router.routes) == 0 {
+		return fmt.Errorf("router has no routes")
+	}
+	return p.fileWatch(ctx, router)
+}
+
+// singleRoute returns a Router that hands all files to handler.
+func singleRoute(handler EventHandler) *Router {
+	return &Router{routes: []route{{handler: handler}}}
+}
@@ -0,0 +1,127 @@
+package FlowG
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatchRouter(t *testing.T) {
+	cases := []struct {
+		name          string
+		unmatched     UnmatchedPolicy
+		wantImport    int
+		wantError     int
+		wantProcessed int
+	}{
+		{"Unmatched files ignored", UnmatchedIgnore, 2, 0, 2},
+		{"Unmatched files left in place", UnmatchedLeave, 2, 0, 2},
+		{"Unmatched files moved to errorDir", UnmatchedError, 1, 2, 2}, // The file and its '.error.txt' sidecar
+	}
+
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		stableQuietPeriod: 100 * time.Millisecond,
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+			err = SetConfig("excludePatterns", []string{"*.tmp"})
+			if err != nil {
+				t.Fatalf("Error setting excludePatterns: %v", err)
+			}
+			defer func() {
+				config.excludePatterns = nil
+			}()
+
+			handled := make(chan string, 4)
+			router := NewRouter(c.unmatched)
+			err = router.Handle("*.csv", func(path string) bool {
+				handled <- "csv"
+				return true
+			})
+			if err != nil {
+				t.Fatalf("Handle() returned error %v", err)
+			}
+			err = router.Handle(`re:\.xml$`, func(path string) bool {
+				handled <- "xml"
+				return true
+			})
+			if err != nil {
+				t.Fatalf("Handle() returned error %v", err)
+			}
+
+			for _, name := range []string{"result.csv", "result.xml", "result.tmp", "audit.log"} {
+				err = os.WriteFile(filepath.Join(config.importDir, name), []byte("data"), 0644)
+				if err != nil {
+					t.Fatalf("Error creating test file: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			result := make(chan error, 1)
+			go func() {
+				result <- FileWatchRouter(ctx, router)
+			}()
+
+			got := map[string]int{}
+			for i := 0; i < 2; i++ {
+				select {
+				case route := <-handled:
+					got[route]++
+				case <-time.After(3 * time.Second):
+					t.Fatalf("Timeout waiting for routed files, got %v", got)
+				}
+			}
+			// Allow the unmatched file to be handled
+			time.Sleep(500 * time.Millisecond)
+			cancel()
+			if err = <-result; err != nil {
+				t.Errorf("FileWatchRouter() returned error %v", err)
+			}
+
+			if got["csv"] != 1 || got["xml"] != 1 {
+				t.Errorf("Expected 1 csv and 1 xml file routed, got %v", got)
+			}
+			importFiles, _ := os.ReadDir(config.importDir)
+			errorFiles, _ := os.ReadDir(config.errorDir)
+			processedFiles, _ := os.ReadDir(config.processedDir)
+			if len(importFiles) != c.wantImport || len(errorFiles) != c.wantError || len(processedFiles) != c.wantProcessed {
+				t.Errorf("Expected: %d import, %d error, %d processed; Got: %d import, %d error, %d processed",
+					c.wantImport, c.wantError, c.wantProcessed, len(importFiles), len(errorFiles), len(processedFiles))
+			}
+		})
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	router := NewRouter(UnmatchedIgnore)
+	if err := router.Handle("[.csv", func(string) bool { return true }); err == nil {
+		t.Errorf("Handle() accepted an invalid glob pattern")
+	}
+	if err := router.Handle("*.csv", nil); err == nil {
+		t.Errorf("Handle() accepted a nil callback")
+	}
+	if err := FileWatchRouter(context.Background(), router); err == nil {
+		t.Errorf("FileWatchRouter() accepted a router without routes")
+	}
+}
@@ -0,0 +1,85 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Defaults used for the file-stability detection when the corresponding config key is not set
+const (
+	defaultStableQuietPeriod = 1 * time.Second
+	defaultStablePolls       = 2
+	defaultStableMaxWait     = 10 * time.Minute
+)
+
+// errNotStable is returned by waitStable when a file kept changing for longer than stableMaxWait.
+var errNotStable = errors.New("file did not stabilise")
+
+// waitStable blocks until the file at path is completely written. A file is considered complete when its size and
+// modification time are unchanged across config.stablePolls polls, spread over config.stableQuietPeriod. If
+// config.stableExclusiveOpen is set, the file must also be openable exclusively, see openExclusive. A file that
+// cannot be opened for lack of permission is not waited for, as waiting does not change that; the callback reports
+// it instead. Returns errNotStable when this does not happen within
+// config.stableMaxWait, the context error when ctx is cancelled, or the error of os.Stat when the file disappears.
+func (p *Pipeline) waitStable(ctx context.Context, path string) error {
+	quietPeriod, polls, maxWait := p.stabilitySettings()
+	interval := quietPeriod / time.Duration(polls)
+	deadline := time.Now().Add(maxWait)
+
+	var last os.FileInfo
+	unchanged := 0
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+			unchanged++
+		} else {
+			unchanged = 0
+		}
+		last = info
+
+		if unchanged >= polls {
+			if !p.config.stableExclusiveOpen {
+				return nil
+			}
+			err = openExclusive(path)
+			if err == nil || errors.Is(err, fs.ErrPermission) {
+				return nil
+			}
+			p.Logging(fmt.Sprintf("File '%s' is unchanged but cannot be opened exclusively yet: %v", path, err), DEBUG)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w within %s", errNotStable, maxWait)
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// stabilitySettings returns the configured quiet period, number of polls and maximum wait, falling back to the
+// defaults for settings that are not configured.
+func (p *Pipeline) stabilitySettings() (time.Duration, int, time.Duration) {
+	quietPeriod := p.config.stableQuietPeriod
+	if quietPeriod == 0 {
+		quietPeriod = defaultStableQuietPeriod
+	}
+	polls := p.config.stablePolls
+	if polls == 0 {
+		polls = defaultStablePolls
+	}
+	maxWait := p.config.stableMaxWait
+	if maxWait == 0 {
+		maxWait = defaultStableMaxWait
+	}
+	return quietPeriod, polls, maxWait
+}
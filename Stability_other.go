@@ -0,0 +1,15 @@
+//go:build !windows
+
+package FlowG
+
+import "os"
+
+// openExclusive opens and closes the file at path for reading. Other systems than Windows do not stop a file that is
+// still being written from being opened, so only the size and modification time show whether it is complete there.
+func openExclusive(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,110 @@
+package FlowG
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitStable(t *testing.T) {
+	cases := []struct {
+		name        string
+		createFile  bool
+		readOnly    bool
+		keepGrowing bool
+		cancel      bool
+		wantErr     error
+	}{
+		{"Stable file", true, false, false, false, nil},
+		{"Read-only file", true, true, false, false, nil},
+		{"File keeps growing", true, false, true, false, errNotStable},
+		{"Missing file", false, false, false, false, os.ErrNotExist},
+		{"Cancelled context", true, false, false, true, context.Canceled},
+	}
+
+	config = &configStruct{
+		glimsDir:     "./glims",
+		importDir:    "./import",
+		processedDir: "./processed",
+		errorDir:     "./error",
+		logDir:       "./log",
+		logPrefix:    "Test",
+		logLvl:       WARNING,
+
+		stableQuietPeriod:   200 * time.Millisecond,
+		stablePolls:         2,
+		stableMaxWait:       time.Second,
+		stableExclusiveOpen: true,
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := createTestFolders()
+			defer func() {
+				err = destroyTestFolders()
+				if err != nil {
+					t.Fatalf("Error cleaning up test folders: %v", err)
+				}
+			}()
+			if err != nil {
+				t.Fatalf("Error creating test folders: %v", err)
+			}
+
+			path := filepath.Join(config.importDir, "testFile.txt")
+			if c.createFile {
+				err = os.WriteFile(path, []byte("data"), 0644)
+				if err != nil {
+					t.Fatalf("Error creating test file: %v", err)
+				}
+			}
+			if c.readOnly {
+				if err = os.Chmod(path, 0444); err != nil {
+					t.Fatalf("Error making test file read-only: %v", err)
+				}
+			}
+
+			stopWriting := make(chan struct{})
+			writerDone := make(chan struct{})
+			go func() {
+				defer close(writerDone)
+				if !c.keepGrowing {
+					return
+				}
+				file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+				if err != nil {
+					return
+				}
+				defer file.Close()
+				for {
+					select {
+					case <-stopWriting:
+						return
+					case <-time.After(50 * time.Millisecond):
+						_, _ = file.WriteString("more data")
+					}
+				}
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if c.cancel {
+				cancel()
+			}
+
+			start := time.Now()
+			err = defaultPipeline().waitStable(ctx, path)
+			close(stopWriting)
+			<-writerDone
+
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("waitStable() returned error %v, wanted %v", err, c.wantErr)
+			}
+			if c.wantErr == nil && time.Since(start) < config.stableQuietPeriod {
+				t.Errorf("waitStable() returned after %s, expected to wait at least the quiet period", time.Since(start))
+			}
+		})
+	}
+}
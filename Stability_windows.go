@@ -0,0 +1,21 @@
+package FlowG
+
+import (
+	"os"
+	"syscall"
+)
+
+// openExclusive opens and closes the file at path for reading without sharing it with other handles. This fails with
+// a sharing violation as long as the instrument still holds the file open, also for read-only files.
+func openExclusive(path string) error {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ, 0, nil, syscall.OPEN_EXISTING,
+		syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return syscall.CloseHandle(handle)
+}
@@ -0,0 +1,58 @@
+package FlowG
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvTable is a CSV file with a header row naming its columns, such as a test catalog or a test mapping.
+type csvTable struct {
+	rows  [][]string // The rows after the header
+	index map[string]int
+}
+
+// readCSVTable reads a CSV table from r. The values are separated by semicolons if the header contains one, by commas
+// otherwise. Returns an error if one of the required columns is missing from the header.
+func readCSVTable(r io.Reader, required ...string) (*csvTable, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	header, _, _ := strings.Cut(string(content), "\n")
+	if strings.Contains(header, ";") {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("missing header")
+	}
+
+	t := &csvTable{rows: rows[1:], index: make(map[string]int)}
+	for i, name := range rows[0] {
+		t.index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range required {
+		if _, ok := t.index[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+	return t, nil
+}
+
+// field returns the value of the column name in row without surrounding whitespace, or an empty string if the table
+// has no such column.
+func (t *csvTable) field(row []string, name string) string {
+	if i, ok := t.index[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
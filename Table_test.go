@@ -0,0 +1,45 @@
+package FlowG
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCSVTable(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		required []string
+		want     []string // The code and testId of the first row
+		wantErr  string
+	}{
+		{"Semicolons", "code;testId\nSC2;SARS-CoV-2\n", []string{"testId"}, []string{"SC2", "SARS-CoV-2"}, ""},
+		{"Commas with spaces", "code, testId\n SC2 , SARS-CoV-2\n", nil, []string{"SC2", "SARS-CoV-2"}, ""},
+		{"Comma in semicolon table", "testId;allowedTexts\nHIV;POS,NEG\n", nil, []string{"", "HIV"}, ""},
+		{"Short row", "testId;code\nHIV\n", nil, nil, "wrong number of fields"},
+		{"Empty", "", nil, nil, "missing header"},
+		{"Missing column", "code;unit\nSC2;\n", []string{"code", "testId"}, nil, "missing testId column"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			table, err := readCSVTable(strings.NewReader(c.content), c.required...)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCSVTable returned error: %v", err)
+			}
+			if len(table.rows) != 1 {
+				t.Fatalf("Expected 1 row, got %d", len(table.rows))
+			}
+			code, testID := table.field(table.rows[0], "code"), table.field(table.rows[0], "testId")
+			if code != c.want[0] || testID != c.want[1] {
+				t.Errorf("Expected code %q and testId %q, got %q and %q", c.want[0], c.want[1], code, testID)
+			}
+		})
+	}
+}